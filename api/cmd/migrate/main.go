@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
-	"github.com/joho/godotenv"
+	"bryce-stabenow/grocer-me/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
@@ -15,18 +19,22 @@ import (
 )
 
 func main() {
-	// Try to load .env file (ignore error if it doesn't exist)
-	_ = godotenv.Load("../../../.env");
-
-	// Get MongoDB URI from environment variable
-	mongoURI := os.Getenv("MONGODB_URI")
-	if mongoURI == "" {
-		log.Fatal("MONGODB_URI environment variable is not set. Please create a .env file or set the environment variable.")
+	// Load config the same way the API does. Only the MongoDB settings matter here.
+	cfg, err := config.Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err == nil {
+		err = cfg.Mongo.Validate()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
 	// Use the SetServerAPIOptions() method to set the version of the Stable API on the client
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(mongoURI).SetServerAPIOptions(serverAPI)
+	opts := options.Client().ApplyURI(cfg.Mongo.URI).SetServerAPIOptions(serverAPI)
 
 	// Create a new client and connect to the server
 	client, err := mongo.Connect(opts)
@@ -46,8 +54,8 @@ func main() {
 	}
 	fmt.Println("Connected to MongoDB!")
 
-	// Get the database (you can change "grocer-me" to your preferred database name)
-	db := client.Database("grocer-me")
+	// Get the configured database
+	db := client.Database(cfg.Mongo.Database)
 
 	// Create User collection with indexes
 	if err := createUserCollection(db); err != nil {
@@ -59,7 +67,52 @@ func main() {
 		log.Fatal("Error creating List collection:", err)
 	}
 
-	fmt.Println("Successfully created User and List collections with indexes!")
+	// Create RefreshToken collection with indexes
+	if err := createRefreshTokenCollection(db); err != nil {
+		log.Fatal("Error creating RefreshToken collection:", err)
+	}
+
+	// Create token revocation collections with indexes
+	if err := createRevocationCollections(db); err != nil {
+		log.Fatal("Error creating revocation collections:", err)
+	}
+
+	// Create Invite collection with indexes
+	if err := createInviteCollection(db); err != nil {
+		log.Fatal("Error creating Invite collection:", err)
+	}
+
+	// Create PasswordReset collection with indexes
+	if err := createPasswordResetCollection(db); err != nil {
+		log.Fatal("Error creating PasswordReset collection:", err)
+	}
+
+	// Create rate limit collection with indexes
+	if err := createRateLimitCollection(db); err != nil {
+		log.Fatal("Error creating rate limit collection:", err)
+	}
+
+	fmt.Println("Successfully created User, List, RefreshToken, revocation, Invite, PasswordReset and rate limit collections with indexes!")
+
+	// Give every existing list item a stable ID
+	if err := backfillListItemIDs(db); err != nil {
+		log.Fatal("Error backfilling list item IDs:", err)
+	}
+
+	// Give every existing list a version for optimistic concurrency
+	if err := backfillListVersions(db); err != nil {
+		log.Fatal("Error backfilling list versions:", err)
+	}
+
+	// Turn the old shared_with user IDs into editor memberships
+	if err := backfillListMembers(db); err != nil {
+		log.Fatal("Error backfilling list members:", err)
+	}
+
+	// Accounts from before email verification keep access to invites and sharing
+	if err := backfillEmailVerified(db); err != nil {
+		log.Fatal("Error backfilling email verification:", err)
+	}
 }
 
 func createUserCollection(db *mongo.Database) error {
@@ -71,15 +124,15 @@ func createUserCollection(db *mongo.Database) error {
 	// Create indexes for User collection
 	indexes := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"email", 1}},
+			Keys:    bson.D{{Key: "email", Value: 1}},
 			Options: options.Index().SetUnique(true).SetName("email_unique"),
 		},
 		{
-			Keys:    bson.D{{"username", 1}},
+			Keys:    bson.D{{Key: "username", Value: 1}},
 			Options: options.Index().SetUnique(true).SetName("username_unique"),
 		},
 		{
-			Keys:    bson.D{{"created_at", 1}},
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
 			Options: options.Index().SetName("created_at_idx"),
 		},
 	}
@@ -119,19 +172,19 @@ func createListCollection(db *mongo.Database) error {
 	// Create indexes for List collection
 	indexes := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"user_id", 1}},
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
 			Options: options.Index().SetName("user_id_idx"),
 		},
 		{
-			Keys:    bson.D{{"created_at", 1}},
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
 			Options: options.Index().SetName("created_at_idx"),
 		},
 		{
-			Keys:    bson.D{{"shared_with", 1}},
-			Options: options.Index().SetName("shared_with_idx"),
+			Keys:    bson.D{{Key: "members.user_id", Value: 1}},
+			Options: options.Index().SetName("members_user_id_idx"),
 		},
 		{
-			Keys:    bson.D{{"user_id", 1}, {"created_at", -1}},
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
 			Options: options.Index().SetName("user_id_created_at_idx"),
 		},
 	}
@@ -141,7 +194,7 @@ func createListCollection(db *mongo.Database) error {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
-	fmt.Println("✓ List collection created with indexes (user_id, created_at, shared_with, user_id+created_at)")
+	fmt.Println("✓ List collection created with indexes (user_id, created_at, members.user_id, user_id+created_at)")
 
 	// Create a sample document structure comment (optional - for documentation)
 	// List document structure:
@@ -152,6 +205,7 @@ func createListCollection(db *mongo.Database) error {
 	//   "description": "Weekly shopping list",
 	//   "items": [
 	//     {
+	//       "_id": ObjectId,
 	//       "name": "Milk",
 	//       "quantity": 1,
 	//       "unit": "gallon",
@@ -160,7 +214,14 @@ func createListCollection(db *mongo.Database) error {
 	//       "added_at": ISODate
 	//     }
 	//   ],
-	//   "shared_with": [ObjectId], // Array of user IDs who have access
+	//   "members": [
+	//     {
+	//       "user_id": ObjectId, // Reference to users collection
+	//       "role": "editor", // viewer, editor or admin
+	//       "added_at": ISODate
+	//     }
+	//   ],
+	//   "version": 1, // Incremented on every write, exposed as the ETag
 	//   "created_at": ISODate,
 	//   "updated_at": ISODate
 	// }
@@ -168,3 +229,352 @@ func createListCollection(db *mongo.Database) error {
 	return nil
 }
 
+func createRefreshTokenCollection(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("refresh_tokens")
+
+	// Create indexes for RefreshToken collection
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("token_hash_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "family_id", Value: 1}},
+			Options: options.Index().SetName("family_id_idx"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("user_id_idx"),
+		},
+		{
+			// Let MongoDB remove tokens once they expire
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	fmt.Println("✓ RefreshToken collection created with indexes (token_hash, family_id, user_id, expires_at TTL)")
+
+	// RefreshToken document structure:
+	// {
+	//   "_id": ObjectId,
+	//   "user_id": ObjectId,
+	//   "family_id": ObjectId, // Shared by every token rotated from the same sign-in
+	//   "token_hash": "sha256 hex",
+	//   "expires_at": ISODate,
+	//   "used_at": ISODate, // Set once the token has been rotated
+	//   "revoked_at": ISODate,
+	//   "created_at": ISODate
+	// }
+
+	return nil
+}
+
+func createRevocationCollections(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("revoked_tokens")
+
+	// Revoked access tokens only matter until they would have expired anyway
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	fmt.Println("✓ RevokedToken collection created with indexes (expires_at TTL)")
+
+	// RevokedToken document structure:
+	// {
+	//   "_id": "jti",
+	//   "user_id": ObjectId,
+	//   "expires_at": ISODate
+	// }
+	//
+	// UserRevocation document structure (user_revocations, keyed by user):
+	// {
+	//   "_id": ObjectId, // User ID
+	//   "revoked_before": ISODate // Access tokens issued before this are invalid
+	// }
+
+	return nil
+}
+
+func createInviteCollection(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("invites")
+
+	// Create indexes
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "list_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("list_id_created_at"),
+		},
+		{
+			// Expired invites can't be redeemed, so let MongoDB clean them up
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	fmt.Println("✓ Invite collection created with indexes (list_id + created_at, expires_at TTL)")
+
+	// Invite document structure:
+	// {
+	//   "_id": ObjectId, // Referenced by the signed invite token
+	//   "list_id": ObjectId,
+	//   "created_by": ObjectId,
+	//   "max_uses": int,
+	//   "uses": int,
+	//   "expires_at": ISODate,
+	//   "revoked_at": ISODate, // Optional
+	//   "created_at": ISODate
+	// }
+
+	return nil
+}
+
+func createPasswordResetCollection(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("password_resets")
+
+	// Create indexes for PasswordReset collection
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("token_hash_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("user_id_idx"),
+		},
+		{
+			// Let MongoDB remove tokens once they expire
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	fmt.Println("✓ PasswordReset collection created with indexes (token_hash, user_id, expires_at TTL)")
+
+	// PasswordReset document structure:
+	// {
+	//   "_id": ObjectId,
+	//   "user_id": ObjectId, // Reference to users collection
+	//   "token_hash": "sha256 hex", // The raw token is only ever emailed
+	//   "expires_at": ISODate,
+	//   "used_at": ISODate, // Optional, set once the token is used
+	//   "created_at": ISODate
+	// }
+
+	return nil
+}
+
+// createRateLimitCollection creates the collection holding rate limit and lockout counters
+func createRateLimitCollection(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("rate_limits")
+
+	// Counters are looked up by _id, so only expiry needs an index
+	indexes := []mongo.IndexModel{
+		{
+			// Let MongoDB remove counters once their window ends
+			Keys:    bson.D{{Key: "reset_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("reset_at_ttl"),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	fmt.Println("✓ rate_limits collection created with indexes (reset_at TTL)")
+
+	// RateLimit document structure:
+	// {
+	//   "_id": "auth:203.0.113.7", // Limiter name and key, e.g. a client IP or email
+	//   "count": 3,
+	//   "reset_at": ISODate // When the window ends and the count starts over
+	// }
+
+	return nil
+}
+
+// backfillListItemIDs assigns an _id to every list item that doesn't have one yet
+func backfillListItemIDs(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	collection := db.Collection("lists")
+
+	// Only lists with at least one item missing an _id
+	filter := bson.M{"items": bson.M{"$elemMatch": bson.M{"_id": bson.M{"$exists": false}}}}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to find lists: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var list struct {
+			ID    bson.RawValue `bson:"_id"`
+			Items []bson.D      `bson:"items"`
+		}
+		if err := cursor.Decode(&list); err != nil {
+			return fmt.Errorf("failed to decode list: %w", err)
+		}
+
+		for i, item := range list.Items {
+			hasID := false
+			for _, field := range item {
+				if field.Key == "_id" {
+					hasID = true
+					break
+				}
+			}
+			if !hasID {
+				// Use the same ObjectID type as the API so IDs are stored identically
+				list.Items[i] = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, item...)
+			}
+		}
+
+		_, err := collection.UpdateOne(
+			ctx,
+			bson.M{"_id": list.ID},
+			bson.M{"$set": bson.M{"items": list.Items}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update list: %w", err)
+		}
+		updated++
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate lists: %w", err)
+	}
+
+	fmt.Printf("✓ Backfilled item IDs on %d list(s)\n", updated)
+
+	return nil
+}
+
+// backfillListVersions sets the initial version on lists created before versioning
+func backfillListVersions(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("lists")
+
+	result, err := collection.UpdateMany(
+		ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update lists: %w", err)
+	}
+
+	fmt.Printf("✓ Backfilled version on %d list(s)\n", result.ModifiedCount)
+
+	return nil
+}
+
+// backfillEmailVerified marks users created before email verification as verified,
+// so the verification policy doesn't lock existing accounts out
+func backfillEmailVerified(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.Collection("users")
+
+	result, err := collection.UpdateMany(
+		ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update users: %w", err)
+	}
+
+	fmt.Printf("✓ Marked %d existing user(s) as verified\n", result.ModifiedCount)
+
+	return nil
+}
+
+// backfillListMembers converts shared_with arrays into members with the editor role,
+// which matches what sharing allowed before roles existed
+func backfillListMembers(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	collection := db.Collection("lists")
+
+	// Done in the update pipeline so the stored user IDs are copied as-is
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"members": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$members", bson.A{}}},
+				bson.M{"$map": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$shared_with", bson.A{}}},
+					"as":    "user_id",
+					"in": bson.M{
+						"user_id":  "$$user_id",
+						"role":     "editor",
+						"added_at": "$$NOW",
+					},
+				}},
+			}},
+		}}},
+		{{Key: "$unset", Value: "shared_with"}},
+	}
+
+	result, err := collection.UpdateMany(ctx, bson.M{"shared_with": bson.M{"$exists": true}}, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to update lists: %w", err)
+	}
+
+	// The old index is replaced by members_user_id_idx
+	if err := collection.Indexes().DropOne(ctx, "shared_with_idx"); err != nil {
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 27 { // 27 = IndexNotFound
+			return fmt.Errorf("failed to drop shared_with index: %w", err)
+		}
+	}
+
+	fmt.Printf("✓ Backfilled members on %d list(s)\n", result.ModifiedCount)
+
+	return nil
+}
@@ -1,45 +1,210 @@
 package config
 
 import (
-	"log"
-	"os"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net/http"
+	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
-var (
-	JWTSecret   string
-	MongoClient *mongo.Client
-	DB          *mongo.Database
-)
+// Config holds every setting the API reads. Load fills it from defaults, then a YAML or TOML
+// file, then environment variables, then command-line flags, each overriding the last.
+//
+// Each field's `env` tag names its environment variable. Its flag is its file key, e.g.
+// -server.port for server.port.
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Mongo     MongoConfig     `yaml:"mongo" toml:"mongo"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Cookies   CookieConfig    `yaml:"cookies" toml:"cookies"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	// Port is the port the API listens on
+	Port int `yaml:"port" toml:"port" env:"PORT"`
+	// AppURL is the web app's base URL, used for links in emails
+	AppURL string `yaml:"app_url" toml:"app_url" env:"APP_URL"`
+
+	// ReadHeaderTimeout bounds how long a client may take to send headers, ReadTimeout and
+	// WriteTimeout the whole request and response, and IdleTimeout how long a keep-alive
+	// connection may wait for its next request. Event streams lift their own write deadline.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	// ShutdownTimeout is how long in-flight requests get to finish after SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// DrainDelay is how long /readyz reports draining before the server stops accepting
+	// connections, giving load balancers time to stop routing to it
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay" env:"DRAIN_DELAY"`
+	// ReadinessTimeout is how long each /readyz dependency check may take
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" toml:"readiness_timeout" env:"READINESS_TIMEOUT"`
+
+	// MaxBodyBytes is the largest request body the API will read
+	MaxBodyBytes int64 `yaml:"max_body_bytes" toml:"max_body_bytes" env:"MAX_BODY_BYTES"`
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For. Only enable it behind a proxy that sets it.
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" toml:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+}
+
+// MongoConfig configures the MongoDB connection
+type MongoConfig struct {
+	URI      string `yaml:"uri" toml:"uri" env:"MONGODB_URI"`
+	Database string `yaml:"database" toml:"database" env:"MONGODB_DATABASE"`
+}
+
+// AuthConfig configures tokens and account verification
+type AuthConfig struct {
+	// JWTSecret signs access tokens, email verification links and invites, through a key per token type
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET"`
+	// AccessTokenTTL is how long a signed access token (JWT) stays valid
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	// RefreshTokenTTL is how long a refresh token can be used to obtain new access tokens
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+	// PasswordResetTTL is how long a password reset link stays valid
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl" env:"PASSWORD_RESET_TTL"`
+	// EmailVerificationTTL is how long an email verification link stays valid
+	EmailVerificationTTL time.Duration `yaml:"email_verification_ttl" toml:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL"`
+	// EmailVerificationPolicy is what an account can't do until its email is verified:
+	// "none", "invite" (create invites) or "share" (create invites and join lists)
+	EmailVerificationPolicy string `yaml:"email_verification_policy" toml:"email_verification_policy" env:"EMAIL_VERIFICATION_POLICY"`
+}
+
+// SigningKey returns the key for JWTs of the given type. Each type gets its own key derived
+// from JWTSecret, so a token issued for one purpose can't be presented as another.
+func (a AuthConfig) SigningKey(tokenType string) []byte {
+	mac := hmac.New(sha256.New, []byte(a.JWTSecret))
+	mac.Write([]byte(tokenType))
+	return mac.Sum(nil)
+}
 
-func Init() {
-	// Load .env file from project root (ignore error if it doesn't exist)
-	_ = godotenv.Load("../.env")
+// CookieConfig configures the auth cookies
+type CookieConfig struct {
+	// Domain scopes the cookies to a domain and its subdomains. Empty means the API's host only.
+	Domain string `yaml:"domain" toml:"domain" env:"COOKIE_DOMAIN"`
+	// Secure only sends the cookies over HTTPS
+	Secure bool `yaml:"secure" toml:"secure" env:"COOKIE_SECURE"`
+	// SameSite is "lax", "strict" or "none". "none" requires Secure.
+	SameSite string `yaml:"same_site" toml:"same_site" env:"COOKIE_SAME_SITE"`
+}
 
-	// Load JWT secret
-	JWTSecret = os.Getenv("JWT_SECRET")
-	if JWTSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is not set. Please create a .env file or set the environment variable.")
+// SameSiteMode returns the SameSite attribute for the auth cookies
+func (c CookieConfig) SameSiteMode() http.SameSite {
+	switch c.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
 	}
+}
 
-	// MongoDB client should be set by main.go after connection
+// CORSConfig configures Cross-Origin Resource Sharing
+type CORSConfig struct {
+	// AllowedOrigins may call the API from a browser. Empty allows only the origin of
+	// Server.AppURL, and "*" allows any origin.
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"`
 }
 
-func SetMongoClient(client *mongo.Client) {
-	MongoClient = client
-	DB = client.Database("grocer-me")
+// RateLimitConfig configures rate limits and account lockout
+type RateLimitConfig struct {
+	// AuthLimit is how many requests a client IP can make to the authentication routes per AuthWindow
+	AuthLimit  int           `yaml:"auth_limit" toml:"auth_limit" env:"AUTH_RATE_LIMIT"`
+	AuthWindow time.Duration `yaml:"auth_window" toml:"auth_window" env:"AUTH_RATE_WINDOW"`
+	// EmailLimit is how many emails can be requested for one address per EmailWindow
+	EmailLimit  int           `yaml:"email_limit" toml:"email_limit" env:"EMAIL_RATE_LIMIT"`
+	EmailWindow time.Duration `yaml:"email_window" toml:"email_window" env:"EMAIL_RATE_WINDOW"`
+
+	// After LockoutThreshold failed password attempts within LockoutWindow an account is locked
+	// for LockoutBaseDelay. Every further failure doubles the lock, up to LockoutMaxDelay.
+	LockoutThreshold int           `yaml:"lockout_threshold" toml:"lockout_threshold" env:"LOCKOUT_THRESHOLD"`
+	LockoutWindow    time.Duration `yaml:"lockout_window" toml:"lockout_window" env:"LOCKOUT_WINDOW"`
+	LockoutBaseDelay time.Duration `yaml:"lockout_base_delay" toml:"lockout_base_delay" env:"LOCKOUT_BASE_DELAY"`
+	LockoutMaxDelay  time.Duration `yaml:"lockout_max_delay" toml:"lockout_max_delay" env:"LOCKOUT_MAX_DELAY"`
 }
 
-func GetMongoURI() string {
-	// Try to load .env file from project root (ignore error if it doesn't exist)
-	_ = godotenv.Load("../.env")
+// MailConfig configures outgoing mail. Without SMTPHost, mail is logged and written to Dir if set.
+type MailConfig struct {
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD"`
+	From         string `yaml:"from" toml:"from" env:"MAIL_FROM"`
+	Dir          string `yaml:"dir" toml:"dir" env:"MAIL_DIR"`
+}
+
+// MetricsConfig configures the /metrics endpoint
+type MetricsConfig struct {
+	// Token, when set, must be sent as a bearer token to read /metrics
+	Token string `yaml:"token" toml:"token" env:"METRICS_TOKEN"`
+}
 
-	uri := os.Getenv("MONGODB_URI")
-	if uri == "" {
-		log.Fatal("MONGODB_URI environment variable is not set. Please create a .env file or set the environment variable.")
+// Defaults returns the configuration used for any setting no source overrides
+func Defaults() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			AppURL:            "http://localhost:3000",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+			MaxBodyBytes:      1 << 20,
+		},
+		Mongo: MongoConfig{
+			Database: "grocer-me",
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:          15 * time.Minute,
+			RefreshTokenTTL:         30 * 24 * time.Hour,
+			PasswordResetTTL:        time.Hour,
+			EmailVerificationTTL:    48 * time.Hour,
+			EmailVerificationPolicy: "invite",
+		},
+		Cookies: CookieConfig{
+			SameSite: "lax",
+		},
+		CORS: CORSConfig{
+			MaxAge: time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			AuthLimit:        20,
+			AuthWindow:       time.Minute,
+			EmailLimit:       3,
+			EmailWindow:      time.Hour,
+			LockoutThreshold: 5,
+			LockoutWindow:    24 * time.Hour,
+			LockoutBaseDelay: time.Minute,
+			LockoutMaxDelay:  time.Hour,
+		},
+		Mail: MailConfig{
+			SMTPPort: 587,
+			From:     "GrocerMe <no-reply@localhost>",
+		},
 	}
-	return uri
 }
 
+var (
+	// Current is the configuration the API runs with. main sets it from Load at startup.
+	Current = Defaults()
+
+	MongoClient *mongo.Client
+	DB          *mongo.Database
+)
+
+// SetMongoClient stores the connected client and opens the configured database
+func SetMongoClient(client *mongo.Client) {
+	MongoClient = client
+	DB = client.Database(Current.Mongo.Database)
+}
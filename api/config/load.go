@@ -0,0 +1,224 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// dotEnvFiles are loaded into the environment, without overriding variables already set.
+// The second covers running from api/ with the .env file at the repo root.
+var dotEnvFiles = []string{".env", "../.env"}
+
+// setting is one leaf field of Config and where it can be set from
+type setting struct {
+	key   string // File key and flag name, e.g. "server.port"
+	env   string
+	value reflect.Value
+}
+
+// Load reads the configuration from defaults, the config file, the environment and the
+// command-line args, in that order, and validates it. The returned error lists every problem.
+func Load(args []string) (*Config, error) {
+	cfg, errs, err := parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := invalid(append(errs, cfg.problems()...)); err != nil {
+		return nil, err
+	}
+
+	// Browsers send origins without a trailing slash and CORS compares them exactly
+	for i, origin := range cfg.CORS.AllowedOrigins {
+		if origin != "*" {
+			cfg.CORS.AllowedOrigins[i] = Origin(origin)
+		}
+	}
+	return cfg, nil
+}
+
+// Parse reads the configuration like Load but leaves validation to the caller, for tools
+// that only need part of it. The file is named by -config or CONFIG_FILE and may be YAML
+// (.yaml, .yml) or TOML (.toml).
+func Parse(args []string) (*Config, error) {
+	cfg, errs, err := parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := invalid(errs); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parse reads the configuration from every source. Values that fail to parse are returned as
+// errs, leaving the setting as it was, so they can be reported alongside validation problems.
+// err is only set for bad command-line usage.
+func parse(args []string) (cfg *Config, errs []error, err error) {
+	cfg = Defaults()
+	settings := cfg.settings()
+
+	// Parse flags first to find the config file, but apply them last
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	var flagValues []func() error
+	for _, s := range settings {
+		usage := fmt.Sprintf("sets %s (env %s)", s.key, s.env)
+		apply := func(raw string) error {
+			flagValues = append(flagValues, func() error { return s.set(raw, "-"+s.key) })
+			return nil
+		}
+		if s.value.Kind() == reflect.Bool {
+			flags.BoolFunc(s.key, usage, func(raw string) error { return apply(raw) })
+		} else {
+			flags.Func(s.key, usage, apply)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	for _, file := range dotEnvFiles {
+		_ = godotenv.Load(file) // Missing files are fine
+	}
+
+	if *configPath == "" {
+		*configPath = os.Getenv("CONFIG_FILE")
+	}
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, s := range settings {
+		if raw := os.Getenv(s.env); raw != "" {
+			if err := s.set(raw, s.env); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for _, apply := range flagValues {
+		if err := apply(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return cfg, errs, nil
+}
+
+// loadFile overlays the settings in a YAML or TOML file, rejecting keys Config doesn't have
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s: extension must be .yaml, .yml or .toml, not %q", path, ext)
+	}
+	return nil
+}
+
+// settings lists every leaf field of cfg, keyed by section and field file keys
+func (cfg *Config) settings() []setting {
+	var settings []setting
+
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Field(i)
+		sectionKey := root.Type().Field(i).Tag.Get("yaml")
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			settings = append(settings, setting{
+				key:   sectionKey + "." + field.Tag.Get("yaml"),
+				env:   field.Tag.Get("env"),
+				value: section.Field(j),
+			})
+		}
+	}
+
+	return settings
+}
+
+// set parses raw into the setting's field. source names where raw came from, for errors.
+func (s setting) set(raw, source string) error {
+	invalid := func(what string) error {
+		return fmt.Errorf("%s: %q is not %s", source, raw, what)
+	}
+
+	switch target := s.value.Addr().Interface().(type) {
+	case *string:
+		*target = raw
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid("true or false")
+		}
+		*target = b
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return invalid("a whole number")
+		}
+		*target = n
+	case *int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return invalid("a whole number")
+		}
+		*target = n
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return invalid("a duration such as 30s or 15m")
+		}
+		*target = d
+	case *[]string:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		*target = values
+	default:
+		panic("config: unsupported setting type for " + s.key)
+	}
+	return nil
+}
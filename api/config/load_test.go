@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate keeps the test from seeing the real environment and .env files
+func isolate(t *testing.T) {
+	t.Helper()
+
+	previous := dotEnvFiles
+	dotEnvFiles = nil
+	t.Cleanup(func() { dotEnvFiles = previous })
+
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range Defaults().settings() {
+		t.Setenv(s.env, "")
+	}
+}
+
+// writeFile writes a config file into a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// valid sets the settings that have no default
+func valid(t *testing.T) {
+	t.Helper()
+	t.Setenv("MONGODB_URI", "mongodb://localhost:27017")
+	t.Setenv("JWT_SECRET", "secret")
+}
+
+func TestLoadLayering(t *testing.T) {
+	isolate(t)
+	valid(t)
+
+	yamlFile := writeFile(t, "config.yaml", `
+server:
+  port: 9000
+  app_url: https://file.example.com
+auth:
+  access_token_ttl: 5m
+cors:
+  allowed_origins: [https://a.example.com, https://b.example.com]
+`)
+	tomlFile := writeFile(t, "config.toml", `
+[server]
+port = 9000
+app_url = "https://file.example.com"
+
+[auth]
+access_token_ttl = "5m"
+
+[cors]
+allowed_origins = ["https://a.example.com", "https://b.example.com"]
+`)
+
+	for _, path := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			// The file overrides defaults, the environment the file, and flags the environment
+			t.Setenv("APP_URL", "https://env.example.com")
+			t.Setenv("PORT", "9100")
+			cfg, err := Load([]string{"-config", path, "-server.port", "9200", "-cookies.secure"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checks := []struct {
+				name      string
+				got, want any
+			}{
+				{"default", cfg.Server.ReadTimeout, 15 * time.Second},
+				{"file", cfg.Auth.AccessTokenTTL, 5 * time.Minute},
+				{"file list", strings.Join(cfg.CORS.AllowedOrigins, " "), "https://a.example.com https://b.example.com"},
+				{"env over file", cfg.Server.AppURL, "https://env.example.com"},
+				{"flag over env", cfg.Server.Port, 9200},
+				{"bool flag", cfg.Cookies.Secure, true},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadNormalizesOrigins(t *testing.T) {
+	isolate(t)
+	valid(t)
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com/,*,https://b.example.com")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(cfg.CORS.AllowedOrigins, " "), "https://a.example.com * https://b.example.com"; got != want {
+		t.Fatalf("allowed origins = %q, want %q", got, want)
+	}
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	isolate(t)
+	valid(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yml", "mongo:\n  database: from-file\n"))
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mongo.Database != "from-file" {
+		t.Fatalf("database = %q, want from-file", cfg.Mongo.Database)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	tests := []struct {
+		name      string
+		noSecrets bool   // Leave out the settings that have no default
+		file      string // Written to config.yaml if set
+		env       map[string]string
+		args      []string
+		wants     []string
+	}{
+		{
+			name:      "missing required settings",
+			noSecrets: true,
+			wants:     []string{"mongo.uri (MONGODB_URI) is required", "auth.jwt_secret (JWT_SECRET) is required"},
+		},
+		{
+			name: "unparseable values alongside invalid ones",
+			env:  map[string]string{"PORT": "abc", "SMTP_PORT": "0", "COOKIE_SAME_SITE": "sometimes"},
+			wants: []string{
+				`PORT: "abc" is not a whole number`,
+				"mail.smtp_port (SMTP_PORT) must be between 1 and 65535",
+				"cookies.same_site (COOKIE_SAME_SITE) must be one of lax, strict or none",
+			},
+		},
+		{
+			name:  "bad flag value",
+			args:  []string{"-auth.access_token_ttl", "soon"},
+			wants: []string{`-auth.access_token_ttl: "soon" is not a duration`},
+		},
+		{
+			name:  "unknown file key",
+			file:  "server:\n  prot: 80\n",
+			wants: []string{"field prot not found"},
+		},
+		{
+			name:  "related settings",
+			env:   map[string]string{"COOKIE_SAME_SITE": "none", "REFRESH_TOKEN_TTL": "1m", "CORS_ALLOWED_ORIGINS": "example.com"},
+			wants: []string{"can only be none when cookies.secure is true", "must be at least auth.access_token_ttl", `got "example.com"`},
+		},
+		{
+			name:  "from without an address",
+			env:   map[string]string{"MAIL_FROM": "GrocerMe"},
+			wants: []string{`mail.from (MAIL_FROM) must be an email address like GrocerMe <no-reply@example.com>, got "GrocerMe"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			if !tt.noSecrets {
+				valid(t)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if tt.file != "" {
+				t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", tt.file))
+			}
+
+			_, err := Load(tt.args)
+			if err == nil {
+				t.Fatal("Load succeeded, want an error")
+			}
+			for _, want := range tt.wants {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error does not mention %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
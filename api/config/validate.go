@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Validate checks the whole configuration and returns an error listing every problem
+func (cfg *Config) Validate() error {
+	return invalid(cfg.problems())
+}
+
+// problems lists everything wrong with the configuration
+func (cfg *Config) problems() []error {
+	var errs []error
+	fail := func(key, env, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s (%s) %s", key, env, fmt.Sprintf(format, args...)))
+	}
+	positive := func(key, env string, d time.Duration) {
+		if d <= 0 {
+			fail(key, env, "must be a positive duration, got %s", d)
+		}
+	}
+
+	// Server
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		fail("server.port", "PORT", "must be between 1 and 65535, got %d", cfg.Server.Port)
+	}
+	if !isAbsoluteURL(cfg.Server.AppURL) {
+		fail("server.app_url", "APP_URL", "must be an absolute http(s) URL, got %q", cfg.Server.AppURL)
+	}
+	positive("server.read_header_timeout", "READ_HEADER_TIMEOUT", cfg.Server.ReadHeaderTimeout)
+	positive("server.read_timeout", "READ_TIMEOUT", cfg.Server.ReadTimeout)
+	positive("server.write_timeout", "WRITE_TIMEOUT", cfg.Server.WriteTimeout)
+	positive("server.idle_timeout", "IDLE_TIMEOUT", cfg.Server.IdleTimeout)
+	positive("server.shutdown_timeout", "SHUTDOWN_TIMEOUT", cfg.Server.ShutdownTimeout)
+	positive("server.readiness_timeout", "READINESS_TIMEOUT", cfg.Server.ReadinessTimeout)
+	if cfg.Server.DrainDelay < 0 {
+		fail("server.drain_delay", "DRAIN_DELAY", "must not be negative, got %s", cfg.Server.DrainDelay)
+	}
+	if cfg.Server.MaxBodyBytes <= 0 {
+		fail("server.max_body_bytes", "MAX_BODY_BYTES", "must be positive, got %d", cfg.Server.MaxBodyBytes)
+	}
+
+	// MongoDB
+	if err := cfg.Mongo.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Auth
+	if cfg.Auth.JWTSecret == "" {
+		fail("auth.jwt_secret", "JWT_SECRET", "is required")
+	}
+	positive("auth.access_token_ttl", "ACCESS_TOKEN_TTL", cfg.Auth.AccessTokenTTL)
+	positive("auth.refresh_token_ttl", "REFRESH_TOKEN_TTL", cfg.Auth.RefreshTokenTTL)
+	positive("auth.password_reset_ttl", "PASSWORD_RESET_TTL", cfg.Auth.PasswordResetTTL)
+	positive("auth.email_verification_ttl", "EMAIL_VERIFICATION_TTL", cfg.Auth.EmailVerificationTTL)
+	if cfg.Auth.RefreshTokenTTL < cfg.Auth.AccessTokenTTL {
+		fail("auth.refresh_token_ttl", "REFRESH_TOKEN_TTL", "must be at least auth.access_token_ttl")
+	}
+	if !slices.Contains([]string{"none", "invite", "share"}, cfg.Auth.EmailVerificationPolicy) {
+		fail("auth.email_verification_policy", "EMAIL_VERIFICATION_POLICY", "must be one of none, invite or share, got %q", cfg.Auth.EmailVerificationPolicy)
+	}
+
+	// Cookies
+	if !slices.Contains([]string{"lax", "strict", "none"}, cfg.Cookies.SameSite) {
+		fail("cookies.same_site", "COOKIE_SAME_SITE", "must be one of lax, strict or none, got %q", cfg.Cookies.SameSite)
+	} else if cfg.Cookies.SameSite == "none" && !cfg.Cookies.Secure {
+		fail("cookies.same_site", "COOKIE_SAME_SITE", "can only be none when cookies.secure is true")
+	}
+
+	// CORS
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin != "*" && !isOrigin(origin) {
+			fail("cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "must hold origins like https://example.com or *, got %q", origin)
+		}
+	}
+	if cfg.CORS.MaxAge < 0 {
+		fail("cors.max_age", "CORS_MAX_AGE", "must not be negative, got %s", cfg.CORS.MaxAge)
+	}
+
+	// Rate limits
+	if cfg.RateLimit.AuthLimit < 1 {
+		fail("rate_limit.auth_limit", "AUTH_RATE_LIMIT", "must be at least 1, got %d", cfg.RateLimit.AuthLimit)
+	}
+	positive("rate_limit.auth_window", "AUTH_RATE_WINDOW", cfg.RateLimit.AuthWindow)
+	if cfg.RateLimit.EmailLimit < 1 {
+		fail("rate_limit.email_limit", "EMAIL_RATE_LIMIT", "must be at least 1, got %d", cfg.RateLimit.EmailLimit)
+	}
+	positive("rate_limit.email_window", "EMAIL_RATE_WINDOW", cfg.RateLimit.EmailWindow)
+	if cfg.RateLimit.LockoutThreshold < 1 {
+		fail("rate_limit.lockout_threshold", "LOCKOUT_THRESHOLD", "must be at least 1, got %d", cfg.RateLimit.LockoutThreshold)
+	}
+	positive("rate_limit.lockout_window", "LOCKOUT_WINDOW", cfg.RateLimit.LockoutWindow)
+	positive("rate_limit.lockout_base_delay", "LOCKOUT_BASE_DELAY", cfg.RateLimit.LockoutBaseDelay)
+	if cfg.RateLimit.LockoutMaxDelay < cfg.RateLimit.LockoutBaseDelay {
+		fail("rate_limit.lockout_max_delay", "LOCKOUT_MAX_DELAY", "must be at least rate_limit.lockout_base_delay")
+	}
+
+	// Mail
+	if cfg.Mail.SMTPPort < 1 || cfg.Mail.SMTPPort > 65535 {
+		fail("mail.smtp_port", "SMTP_PORT", "must be between 1 and 65535, got %d", cfg.Mail.SMTPPort)
+	}
+	if cfg.Mail.From == "" {
+		fail("mail.from", "MAIL_FROM", "is required")
+	} else if _, err := mail.ParseAddress(cfg.Mail.From); err != nil {
+		fail("mail.from", "MAIL_FROM", "must be an email address like GrocerMe <no-reply@example.com>, got %q", cfg.Mail.From)
+	}
+
+	return errs
+}
+
+// invalid joins configuration problems into one error, or returns nil if there are none
+func invalid(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+}
+
+// Validate checks the MongoDB settings on their own, for tools that only need the database
+func (m MongoConfig) Validate() error {
+	var errs []error
+	if m.URI == "" {
+		errs = append(errs, errors.New("mongo.uri (MONGODB_URI) is required"))
+	}
+	if m.Database == "" {
+		errs = append(errs, errors.New("mongo.database (MONGODB_DATABASE) is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// isAbsoluteURL reports whether s is an http or https URL with a host
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isOrigin reports whether s is a bare origin: a scheme and host with no path
+func isOrigin(s string) bool {
+	u, err := url.Parse(s)
+	return isAbsoluteURL(s) && err == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.User == nil
+}
+
+// Origin returns the origin of a URL, e.g. https://example.com for https://example.com/app
+func Origin(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
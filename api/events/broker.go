@@ -0,0 +1,150 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types pushed to list subscribers
+const (
+	ItemAdded   = "item_added"
+	ItemUpdated = "item_updated"
+	ItemChecked = "item_checked"
+	ItemDeleted = "item_deleted"
+	ListRenamed = "list_renamed"
+	ListUpdated = "list_updated"
+	ListDeleted = "list_deleted"
+
+	MemberAdded   = "member_added"
+	MemberUpdated = "member_updated"
+	MemberRemoved = "member_removed"
+	OwnerChanged  = "owner_changed"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before it is dropped
+const subscriberBuffer = 32
+
+// Event describes a change to a list
+type Event struct {
+	Type        string           `json:"type"`
+	ListID      string           `json:"list_id"`
+	Version     int64            `json:"version"`
+	ActorID     string           `json:"actor_id"`
+	Item        *models.ListItem `json:"item,omitempty"`
+	ItemID      string           `json:"item_id,omitempty"`
+	Name        string           `json:"name,omitempty"`
+	Description string           `json:"description,omitempty"`
+	MemberID    string           `json:"member_id,omitempty"`
+	Role        string           `json:"role,omitempty"`
+	At          time.Time        `json:"at"`
+}
+
+// Broker fans out list events to the subscribers of each list.
+// It only reaches subscribers connected to this process.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[primitive.ObjectID]map[chan Event]primitive.ObjectID // list ID -> channel -> user ID
+	closed      bool
+}
+
+// NewBroker creates an empty Broker
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[primitive.ObjectID]map[chan Event]primitive.ObjectID),
+	}
+}
+
+// Subscribe registers a user for events on a list. The returned channel is closed when the
+// subscriber falls too far behind, the user is disconnected or unsubscribe is called.
+func (b *Broker) Subscribe(listID, userID primitive.ObjectID) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[listID] == nil {
+		b.subscribers[listID] = make(map[chan Event]primitive.ObjectID)
+	}
+	b.subscribers[listID][ch] = userID
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(listID, ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends an event to every subscriber of the list without blocking.
+// Subscribers whose buffer is full are dropped so they reconnect and reload.
+func (b *Broker) Publish(listID primitive.ObjectID, event Event) {
+	if event.ListID == "" {
+		event.ListID = listID.Hex()
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[listID] {
+		select {
+		case ch <- event:
+		default:
+			b.remove(listID, ch)
+		}
+	}
+}
+
+// Disconnect closes every stream the user has open on a list, e.g. after losing access to it.
+// Events already buffered for the user are still delivered before the channel reports closed.
+func (b *Broker) Disconnect(listID, userID primitive.ObjectID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch, subscriber := range b.subscribers[listID] {
+		if subscriber == userID {
+			b.remove(listID, ch)
+		}
+	}
+}
+
+// Close closes every open stream and any opened afterwards, so the server can shut down
+// without waiting for clients to hang up
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for listID, subs := range b.subscribers {
+		for ch := range subs {
+			b.remove(listID, ch)
+		}
+	}
+}
+
+// remove closes and forgets a subscriber. Callers must hold b.mu.
+func (b *Broker) remove(listID primitive.ObjectID, ch chan Event) {
+	subs, ok := b.subscribers[listID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[ch]; !ok {
+		return
+	}
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(b.subscribers, listID)
+	}
+}
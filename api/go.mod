@@ -3,11 +3,13 @@ module bryce-stabenow/grocer-me
 go 1.25.5
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/joho/godotenv v1.5.1
 	go.mongodb.org/mongo-driver v1.15.0
 	go.mongodb.org/mongo-driver/v2 v2.4.1
 	golang.org/x/crypto v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
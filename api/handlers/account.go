@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"bryce-stabenow/grocer-me/events"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/middleware"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HandleUpdateProfile updates the current user's name and avatar
+func (h *Handler) HandleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Parse request body
+	var req models.UpdateProfileRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, ok := h.fetchUser(ctx, w, userID)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Only change the fields that were sent
+	profile := models.Profile{}
+	if user.Profile != nil {
+		profile = *user.Profile
+	}
+	if req.FirstName != nil {
+		profile.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		profile.LastName = *req.LastName
+	}
+	if req.AvatarURL != nil {
+		profile.AvatarURL = *req.AvatarURL
+	}
+
+	updatedUser, err := h.Users.Update(ctx, userID, store.UserUpdate{Profile: &profile})
+	if err != nil {
+		writeUserStoreError(w, err, "Failed to update profile")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, userToPublic(updatedUser))
+}
+
+// HandleChangePassword changes the current user's password after confirming the current one.
+// Every other session is signed out; this one gets fresh tokens.
+func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Parse request body
+	var req models.ChangePasswordRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Confirm the current password
+	user, ok := h.fetchUser(ctx, w, userID)
+	if !ok {
+		return // Error response already sent
+	}
+	if !h.checkPassword(ctx, w, user, req.CurrentPassword) {
+		return // Error response already sent
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 10)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to hash password")
+		return
+	}
+
+	passwordHash := string(hashedPassword)
+	if _, err := h.Users.Update(ctx, userID, store.UserUpdate{PasswordHash: &passwordHash}); err != nil {
+		writeUserStoreError(w, err, "Failed to update password")
+		return
+	}
+
+	// Outstanding reset links and every existing session stop working
+	if err := h.PasswordResets.InvalidateUser(ctx, userID, time.Now()); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to invalidate reset tokens")
+		return
+	}
+	if err := h.revokeAllSessions(ctx, r, userID); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke sessions")
+		return
+	}
+
+	// Keep this device signed in with a new session (sets both cookies)
+	tokens, err := h.issueTokens(ctx, w, userID, primitive.NewObjectID())
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate token")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, tokens)
+}
+
+// HandleChangeEmail changes the current user's email after confirming their password.
+// The new address starts out unverified and is sent a verification link.
+func (h *Handler) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Parse request body
+	var req models.ChangeEmailRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Confirm the password
+	user, ok := h.fetchUser(ctx, w, userID)
+	if !ok {
+		return // Error response already sent
+	}
+	if !h.checkPassword(ctx, w, user, req.Password) {
+		return // Error response already sent
+	}
+
+	if req.Email == user.Email {
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeEmailUnchanged, "That is already your email address")
+		return
+	}
+
+	// Usernames default to the signup email, so keep them in step
+	update := store.UserUpdate{Email: &req.Email}
+	if user.Username == user.Email {
+		update.Username = &req.Email
+	}
+
+	updatedUser, err := h.Users.Update(ctx, userID, update)
+	if err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			utils.ErrorResponse(w, http.StatusConflict, utils.CodeEmailTaken, "Email already exists")
+			return
+		}
+		writeUserStoreError(w, err, "Failed to update email")
+		return
+	}
+
+	// Verify the new address and let the old one know it was replaced
+	if err := h.sendVerificationEmail(updatedUser); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", userID.Hex(), err)
+	}
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your GrocerMe email address was changed",
+		Body: fmt.Sprintf("The email address on your GrocerMe account was changed to %s.\n\n"+
+			"If you didn't do this, reset your password and contact us.\n", req.Email),
+	})
+
+	utils.JSONResponse(w, http.StatusOK, userToPublic(updatedUser))
+}
+
+// HandleDeleteAccount deletes the current user after confirming their password.
+// Owned lists go to their most senior member, or are deleted if nobody else is on them.
+// The user is removed from every other list, their items are anonymised and their sessions end.
+func (h *Handler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Parse request body
+	var req models.DeleteAccountRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	// Cleaning up every list can take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Confirm the password
+	user, ok := h.fetchUser(ctx, w, userID)
+	if !ok {
+		return // Error response already sent
+	}
+	if !h.checkPassword(ctx, w, user, req.Password) {
+		return // Error response already sent
+	}
+
+	// Every step below can be safely repeated, so the user is only deleted
+	// once the rest succeeded and a failed attempt can just be retried
+	if err := h.leaveAllLists(ctx, userID); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to remove you from your lists")
+		return
+	}
+	if err := h.Lists.AnonymizeItems(ctx, userID); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to anonymise your items")
+		return
+	}
+
+	now := time.Now()
+	if err := h.Invites.RevokeCreatedBy(ctx, userID, now); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke your invites")
+		return
+	}
+	if err := h.PasswordResets.InvalidateUser(ctx, userID, now); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to invalidate password reset links")
+		return
+	}
+	if err := h.revokeAllSessions(ctx, r, userID); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke sessions")
+		return
+	}
+
+	if err := h.Users.Delete(ctx, userID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to delete account")
+		return
+	}
+
+	// Clear the auth cookies by setting them with an expired expiration time
+	clearAuthCookies(w)
+
+	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "Account deleted successfully"})
+}
+
+// leaveAllLists hands over or deletes the user's own lists and removes them from everyone else's
+func (h *Handler) leaveAllLists(ctx context.Context, userID primitive.ObjectID) error {
+	lists, err := h.Lists.FindForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range lists {
+		list := &lists[i]
+
+		if list.UserID != userID {
+			updatedList, err := h.Lists.RemoveMember(ctx, list.ID, userID, store.AnyVersion)
+			if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrMemberNotFound) {
+				continue // Deleted or left concurrently
+			}
+			if err != nil {
+				return err
+			}
+			h.publishMemberRemoved(updatedList, userID, userID)
+			continue
+		}
+
+		successor, ok := successorOf(list)
+		if !ok {
+			// Nobody else uses the list
+			if err := h.Lists.Delete(ctx, list.ID, store.AnyVersion); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+			h.Events.Publish(list.ID, events.Event{
+				Type:    events.ListDeleted,
+				Version: list.Version + 1,
+				ActorID: userID.Hex(),
+			})
+			continue
+		}
+
+		// Hand the list over, then drop the former owner's new membership
+		transferred, err := h.Lists.TransferOwnership(ctx, list.ID, userID, successor, store.AnyVersion, models.RoleViewer)
+		if err != nil {
+			return err
+		}
+		h.Events.Publish(list.ID, events.Event{
+			Type:     events.OwnerChanged,
+			Version:  transferred.Version,
+			ActorID:  userID.Hex(),
+			MemberID: successor.Hex(),
+		})
+
+		updatedList, err := h.Lists.RemoveMember(ctx, list.ID, userID, store.AnyVersion)
+		if err != nil {
+			return err
+		}
+		h.publishMemberRemoved(updatedList, userID, userID)
+	}
+
+	return nil
+}
+
+// successorOf picks the member who inherits a list when its owner leaves:
+// the highest role wins, then whoever joined first
+func successorOf(list *models.List) (primitive.ObjectID, bool) {
+	if len(list.Members) == 0 {
+		return primitive.NilObjectID, false
+	}
+
+	members := append([]models.Membership{}, list.Members...)
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].Role != members[j].Role {
+			return members[i].Role.AtLeast(members[j].Role)
+		}
+		return members[i].AddedAt.Before(members[j].AddedAt)
+	})
+	return members[0].UserID, true
+}
+
+// fetchUser loads the user, sending an error response if that fails
+func (h *Handler) fetchUser(ctx context.Context, w http.ResponseWriter, userID primitive.ObjectID) (*models.User, bool) {
+	user, err := h.Users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeUserNotFound, "User not found")
+			return nil, false
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find user")
+		return nil, false
+	}
+	return user, true
+}
+
+// checkPassword confirms the password before a sensitive account change.
+// Wrong guesses count towards the same lockout as failed sign-ins.
+func (h *Handler) checkPassword(ctx context.Context, w http.ResponseWriter, user *models.User, password string) bool {
+	if retryAfter := h.Lockout.Check(ctx, user.Email); retryAfter > 0 {
+		middleware.TooManyRequests(w, retryAfter)
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		h.Lockout.Fail(ctx, user.Email)
+		utils.ErrorResponse(w, http.StatusForbidden, utils.CodeIncorrectPassword, "Incorrect password")
+		return false
+	}
+	h.Lockout.Succeed(ctx, user.Email)
+	return true
+}
+
+// writeUserStoreError maps user store errors to HTTP responses
+func writeUserStoreError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, store.ErrNotFound) {
+		utils.ErrorResponse(w, http.StatusNotFound, utils.CodeUserNotFound, "User not found")
+		return
+	}
+	utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, message)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+func TestUpdateProfile(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	w := s.do(request{method: http.MethodPatch, path: "/me", token: user.Token, body: map[string]string{"first_name": "Annie"}})
+	expectStatus(t, w, http.StatusOK)
+	var me models.UserPublic
+	decode(t, w, &me)
+	if me.Profile == nil || me.Profile.FirstName != "Annie" || me.Profile.LastName != "User" {
+		t.Fatalf("profile = %+v, want first name changed and last name kept", me.Profile)
+	}
+
+	w = s.do(request{method: http.MethodPatch, path: "/me", token: user.Token, body: map[string]string{"last_name": "  "}})
+	expectError(t, w, http.StatusBadRequest, utils.CodeValidationFailed)
+}
+
+func TestChangePassword(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	w := s.do(request{method: http.MethodPost, path: "/me/password", token: user.Token, body: map[string]string{"current_password": "wrong-password", "new_password": "new-password"}})
+	expectError(t, w, http.StatusForbidden, utils.CodeIncorrectPassword)
+
+	w = s.do(request{method: http.MethodPost, path: "/me/password", token: user.Token, body: map[string]string{"current_password": testPassword, "new_password": "new-password"}})
+	expectStatus(t, w, http.StatusOK)
+	var tokens models.TokenResponse
+	decode(t, w, &tokens)
+
+	// This device keeps a fresh session, the old one is gone
+	expectStatus(t, s.do(request{method: http.MethodGet, path: "/me", token: tokens.Token}), http.StatusOK)
+	w = s.do(request{method: http.MethodGet, path: "/me", token: user.Token})
+	expectError(t, w, http.StatusUnauthorized, utils.CodeSessionRevoked)
+	w = s.do(request{method: http.MethodPost, path: "/token/refresh", body: map[string]string{"refresh_token": user.RefreshToken}})
+	expectError(t, w, http.StatusUnauthorized, utils.CodeInvalidRefreshToken)
+}
+
+func TestChangeEmail(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+	s.signup("bob@example.com")
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		status int
+		code   utils.ErrorCode
+	}{
+		{"wrong password", map[string]string{"email": "ann@example.org", "password": "wrong-password"}, http.StatusForbidden, utils.CodeIncorrectPassword},
+		{"same address", map[string]string{"email": user.Email, "password": testPassword}, http.StatusBadRequest, utils.CodeEmailUnchanged},
+		{"taken address", map[string]string{"email": "bob@example.com", "password": testPassword}, http.StatusConflict, utils.CodeEmailTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: http.MethodPost, path: "/me/email", token: user.Token, body: tt.body})
+			expectError(t, w, tt.status, tt.code)
+		})
+	}
+
+	w := s.do(request{method: http.MethodPost, path: "/me/email", token: user.Token, body: map[string]string{"email": "ann@example.org", "password": testPassword}})
+	expectStatus(t, w, http.StatusOK)
+	var me models.UserPublic
+	decode(t, w, &me)
+	if me.Email != "ann@example.org" || me.Username != "ann@example.org" || me.EmailVerified {
+		t.Fatalf("after change email = %q, username = %q, verified = %v", me.Email, me.Username, me.EmailVerified)
+	}
+	s.nextMail(user.Email, "email address was changed")
+}
+
+func TestDeleteAccount(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	editor := s.signup("editor@example.com")
+	viewer := s.signup("viewer@example.com")
+
+	shared := s.createList(owner, "Shared")
+	s.addMember(shared.ID, viewer, models.RoleViewer)
+	s.addMember(shared.ID, editor, models.RoleEditor)
+	s.addItem(owner, shared.ID, "Milk")
+	private := s.createList(owner, "Private")
+	theirs := s.createList(editor, "Theirs")
+	s.addMember(theirs.ID, owner, models.RoleEditor)
+
+	w := s.do(request{method: http.MethodDelete, path: "/me", token: owner.Token, body: map[string]string{"password": "wrong-password"}})
+	expectError(t, w, http.StatusForbidden, utils.CodeIncorrectPassword)
+
+	w = s.do(request{method: http.MethodDelete, path: "/me", token: owner.Token, body: map[string]string{"password": testPassword}})
+	expectStatus(t, w, http.StatusOK)
+
+	// The most senior member inherits the shared list, with the owner's items anonymised
+	w = s.do(request{method: http.MethodGet, path: "/lists/" + shared.ID, token: editor.Token})
+	expectStatus(t, w, http.StatusOK)
+	var list models.ListResponse
+	decode(t, w, &list)
+	if list.UserID != editor.ID.Hex() || len(list.SharedWith) != 1 || !list.Items[0].AddedBy.IsZero() {
+		t.Fatalf("inherited list = owner %s, members %+v, item added by %s", list.UserID, list.SharedWith, list.Items[0].AddedBy.Hex())
+	}
+
+	// Lists nobody else used are deleted, and the owner is taken off other lists
+	w = s.do(request{method: http.MethodGet, path: "/lists/" + private.ID, token: editor.Token})
+	expectError(t, w, http.StatusNotFound, utils.CodeListNotFound)
+	w = s.do(request{method: http.MethodGet, path: "/lists/" + theirs.ID, token: editor.Token})
+	decode(t, w, &list)
+	if len(list.SharedWith) != 0 {
+		t.Fatalf("deleted user is still a member: %+v", list.SharedWith)
+	}
+
+	w = s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": owner.Email, "password": testPassword}})
+	expectError(t, w, http.StatusUnauthorized, utils.CodeInvalidCredentials)
+}
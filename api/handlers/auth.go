@@ -2,48 +2,48 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/middleware"
 	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
 	"bryce-stabenow/grocer-me/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // HandleSignup handles user registration
-func HandleSignup(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	var req models.SignupRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
 	// Check if email already exists
-	collection := config.DB.Collection("users")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var existingUser models.User
-	err := collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&existingUser)
+	_, err := h.Users.FindByEmail(ctx, req.Email)
 	if err == nil {
-		utils.ErrorResponse(w, http.StatusConflict, "Email already exists")
+		utils.ErrorResponse(w, http.StatusConflict, utils.CodeEmailTaken, "Email already exists")
 		return
 	}
-	if err != mongo.ErrNoDocuments {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to check email")
+	if !errors.Is(err, store.ErrNotFound) {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to check email")
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 10)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to hash password")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to hash password")
 		return
 	}
 
@@ -56,7 +56,7 @@ func HandleSignup(w http.ResponseWriter, r *http.Request) {
 	if req.AvatarURL != nil && *req.AvatarURL != "" {
 		profile.AvatarURL = *req.AvatarURL
 	}
-	
+
 	user := models.User{
 		ID:           primitive.NewObjectID(),
 		Email:        req.Email,
@@ -67,91 +67,94 @@ func HandleSignup(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:    now,
 	}
 
-	_, err = collection.InsertOne(ctx, user)
+	err = h.Users.Create(ctx, &user)
+	if errors.Is(err, store.ErrDuplicate) {
+		utils.ErrorResponse(w, http.StatusConflict, utils.CodeEmailTaken, "Email already exists")
+		return
+	}
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create user")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to create user")
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateToken(user.ID.Hex())
+	// Ask the user to confirm they own the address. Signup still succeeds if this fails,
+	// they can request another link later.
+	if err := h.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID.Hex(), err)
+	}
+
+	// Issue an access token and start a new refresh token family (sets both cookies)
+	tokens, err := h.issueTokens(ctx, w, user.ID, primitive.NewObjectID())
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to generate token")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate token")
 		return
 	}
 
-	// Set JWT as HTTP-only cookie (24 hours expiration to match token)
-	utils.SetCookie(w, "jwt_token", token, 3600*24, "/", "", false, true)
-
 	// Return response
 	utils.JSONResponse(w, http.StatusCreated, models.AuthResponse{
-		Token: token,
-		User: &models.UserPublic{
-			ID:        user.ID.Hex(),
-			Email:     user.Email,
-			Username:  user.Username,
-			Profile:   user.Profile,
-			CreatedAt: user.CreatedAt,
-		},
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         userToPublic(&user),
 	})
 }
 
 // HandleSignin handles user login
-func HandleSignin(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleSignin(w http.ResponseWriter, r *http.Request) {
 	var req models.SigninRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
-	// Find user by email
-	collection := config.DB.Collection("users")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	// Refuse attempts while the account is locked after too many failures
+	if retryAfter := h.Lockout.Check(ctx, req.Email); retryAfter > 0 {
+		middleware.TooManyRequests(w, retryAfter)
+		return
+	}
+
+	// Find user by email
+	user, err := h.Users.FindByEmail(ctx, req.Email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid email or password")
+		if errors.Is(err, store.ErrNotFound) {
+			h.Lockout.Fail(ctx, req.Email)
+			utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeInvalidCredentials, "Invalid email or password")
 			return
 		}
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to find user")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find user")
 		return
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid email or password")
+		h.Lockout.Fail(ctx, req.Email)
+		utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeInvalidCredentials, "Invalid email or password")
 		return
 	}
+	h.Lockout.Succeed(ctx, req.Email)
 
-	// Generate JWT token
-	token, err := generateToken(user.ID.Hex())
+	// Issue an access token and start a new refresh token family (sets both cookies)
+	tokens, err := h.issueTokens(ctx, w, user.ID, primitive.NewObjectID())
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to generate token")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate token")
 		return
 	}
 
-	// Set JWT as HTTP-only cookie (24 hours expiration to match token)
-	utils.SetCookie(w, "jwt_token", token, 3600*24, "/", "", false, true)
-
 	// Return response
 	utils.JSONResponse(w, http.StatusOK, models.AuthResponse{
-		Token: token,
-		User: &models.UserPublic{
-			ID:        user.ID.Hex(),
-			Email:     user.Email,
-			Username:  user.Username,
-			Profile:   user.Profile,
-			CreatedAt: user.CreatedAt,
-		},
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         userToPublic(user),
 	})
 }
 
 // HandleGetMe returns the current user's information
-func HandleGetMe(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleGetMe(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -159,18 +162,16 @@ func HandleGetMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find user by ID
-	collection := config.DB.Collection("users")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	user, err := h.Users.FindByID(ctx, userID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.ErrorResponse(w, http.StatusNotFound, "User not found")
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeUserNotFound, "User not found")
 			return
 		}
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to find user")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find user")
 		return
 	}
 
@@ -178,25 +179,117 @@ func HandleGetMe(w http.ResponseWriter, r *http.Request) {
 	utils.JSONResponse(w, http.StatusOK, user)
 }
 
-// HandleLogout handles user logout by clearing the JWT cookie
-func HandleLogout(w http.ResponseWriter, r *http.Request) {
-	// Clear the JWT cookie by setting it with an expired expiration time
-	utils.SetCookie(w, "jwt_token", "", -1, "/", "", false, true)
-	
+// HandleLogout handles user logout by revoking the current session and clearing the auth cookies.
+// It is public so that a session whose access token already expired can still be ended.
+func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	// Revoke the access token so copies of it stop working immediately
+	if token, err := h.Auth.Authenticate(r); err == nil && token.ID != "" {
+		userID, err := primitive.ObjectIDFromHex(token.UserID)
+		if err == nil {
+			if err := h.Auth.Revocations.RevokeToken(ctx, token.ID, userID, token.ExpiresAt); err != nil {
+				utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke session")
+				return
+			}
+		}
+	}
+
+	// Revoke the refresh token family so the session can't be resumed
+	if cookie, err := r.Cookie(refreshTokenCookie); err == nil && cookie.Value != "" {
+		token, err := h.RefreshTokens.FindByHash(ctx, hashToken(cookie.Value))
+		if err == nil {
+			if err := h.RefreshTokens.RevokeFamily(ctx, token.FamilyID, now); err != nil {
+				utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke session")
+				return
+			}
+		} else if !errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke session")
+			return
+		}
+	}
+
+	// Clear the auth cookies by setting them with an expired expiration time
+	clearAuthCookies(w)
+
 	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
 }
 
-// generateToken creates a JWT token for the given user ID
-func generateToken(userID string) (string, error) {
-	// Token expires in 24 hours
-	expirationTime := time.Now().Add(24 * time.Hour)
+// HandleLogoutAll signs the user out on every device by revoking all their tokens
+func (h *Handler) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.revokeAllSessions(ctx, r, userID); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke sessions")
+		return
+	}
+
+	// Clear the auth cookies by setting them with an expired expiration time
+	clearAuthCookies(w)
+
+	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "Logged out everywhere successfully"})
+}
+
+// revokeAllSessions invalidates every access and refresh token the user holds
+func (h *Handler) revokeAllSessions(ctx context.Context, r *http.Request, userID primitive.ObjectID) error {
+	// iat has millisecond precision, like the times the revocation store keeps, so a new
+	// session started straight after this stays valid. A sign-in in this same millisecond
+	// counts as concurrent with the revocation, except for the caller's own token.
+	now := time.Now().Truncate(time.Millisecond)
+	if err := h.Auth.Revocations.RevokeAllBefore(ctx, userID, now); err != nil {
+		return err
+	}
+	if token, ok := utils.GetToken(r); ok && token.ID != "" {
+		if err := h.Auth.Revocations.RevokeToken(ctx, token.ID, userID, token.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return h.RefreshTokens.RevokeUser(ctx, userID, now)
+}
+
+// generateToken creates a short-lived JWT access token for the given user ID
+func generateToken(userID string) (string, time.Time, error) {
+	now := time.Now()
+	expirationTime := now.Add(config.Current.Auth.AccessTokenTTL)
+
+	// jti lets a single token be revoked before it expires
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
 	claims := jwt.MapClaims{
+		"typ":     middleware.AccessTokenType,
 		"user_id": userID,
+		"jti":     tokenID,
 		"exp":     expirationTime.Unix(),
-		"iat":     time.Now().Unix(),
+		"iat":     middleware.IssuedAtClaim(now),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.JWTSecret))
+	signed, err := token.SignedString(config.Current.Auth.SigningKey(middleware.AccessTokenType))
+	return signed, expirationTime, err
+}
+
+// userToPublic converts a User model to the UserPublic returned to its owner
+func userToPublic(user *models.User) *models.UserPublic {
+	return &models.UserPublic{
+		ID:            user.ID.Hex(),
+		Email:         user.Email,
+		Username:      user.Username,
+		Profile:       user.Profile,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+	}
 }
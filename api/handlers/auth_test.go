@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/middleware"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSignupAndSignin(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	w := s.do(request{method: http.MethodGet, path: "/me", token: user.Token})
+	expectStatus(t, w, http.StatusOK)
+	var me models.User
+	decode(t, w, &me)
+	if me.Email != user.Email {
+		t.Errorf("GET /me email = %q, want %q", me.Email, user.Email)
+	}
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		status int
+		code   utils.ErrorCode
+	}{
+		{"correct password", map[string]string{"email": user.Email, "password": testPassword}, http.StatusOK, ""},
+		{"wrong password", map[string]string{"email": user.Email, "password": "wrong-password"}, http.StatusUnauthorized, utils.CodeInvalidCredentials},
+		{"unknown email", map[string]string{"email": "nobody@example.com", "password": testPassword}, http.StatusUnauthorized, utils.CodeInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: http.MethodPost, path: "/signin", body: tt.body})
+			if tt.code == "" {
+				expectStatus(t, w, tt.status)
+				return
+			}
+			expectError(t, w, tt.status, tt.code)
+		})
+	}
+}
+
+func TestSignupRejectsTakenEmail(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("ann@example.com")
+
+	w := s.do(request{method: http.MethodPost, path: "/signup", body: map[string]string{
+		"email": "ann@example.com", "password": testPassword, "first_name": "Ann", "last_name": "Other",
+	}})
+	expectError(t, w, http.StatusConflict, utils.CodeEmailTaken)
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		token string
+		code  utils.ErrorCode
+	}{
+		{"no token", "", utils.CodeAuthRequired},
+		{"garbage token", "not-a-jwt", utils.CodeInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: http.MethodGet, path: "/me", token: tt.token})
+			expectError(t, w, http.StatusUnauthorized, tt.code)
+		})
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	refresh := func(token string) (models.TokenResponse, int, utils.ErrorCode) {
+		w := s.do(request{method: http.MethodPost, path: "/token/refresh", body: map[string]string{"refresh_token": token}})
+		if w.Code != http.StatusOK {
+			var apiErr utils.APIError
+			decode(t, w, &apiErr)
+			return models.TokenResponse{}, w.Code, apiErr.Code
+		}
+		var tokens models.TokenResponse
+		decode(t, w, &tokens)
+		return tokens, w.Code, ""
+	}
+
+	rotated, status, _ := refresh(user.RefreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("first refresh status = %d, want 200", status)
+	}
+	if rotated.RefreshToken == user.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+	expectStatus(t, s.do(request{method: http.MethodGet, path: "/me", token: rotated.Token}), http.StatusOK)
+
+	// Presenting the old token again means it was copied, so the whole family goes
+	if _, status, code := refresh(user.RefreshToken); status != http.StatusUnauthorized || code != utils.CodeRefreshTokenReused {
+		t.Fatalf("reused refresh = %d %q, want 401 %q", status, code, utils.CodeRefreshTokenReused)
+	}
+	if _, status, code := refresh(rotated.RefreshToken); status != http.StatusUnauthorized || code != utils.CodeInvalidRefreshToken {
+		t.Fatalf("refresh after reuse = %d %q, want 401 %q", status, code, utils.CodeInvalidRefreshToken)
+	}
+}
+
+func TestOnlyAccessTokensAuthenticate(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	verification, err := generateVerificationToken(user.ID, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invite, err := generateInviteToken(&models.Invite{ID: primitive.NewObjectID(), ListID: primitive.NewObjectID(), CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An access token signed with the shared secret rather than the access token key
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":     middleware.AccessTokenType,
+		"user_id": user.ID.Hex(),
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(config.Current.Auth.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"verification link", verification},
+		{"invite", invite},
+		{"shared secret", forged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: http.MethodGet, path: "/me", token: tt.token})
+			expectError(t, w, http.StatusUnauthorized, utils.CodeInvalidToken)
+		})
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	expectStatus(t, s.do(request{method: http.MethodPost, path: "/logout", token: user.Token}), http.StatusOK)
+
+	w := s.do(request{method: http.MethodGet, path: "/me", token: user.Token})
+	expectError(t, w, http.StatusUnauthorized, utils.CodeSessionRevoked)
+}
+
+// unreachableRevocations is a RevocationStore whose database is down
+type unreachableRevocations struct {
+	store.RevocationStore
+}
+
+func (unreachableRevocations) IsRevoked(ctx context.Context, tokenID string, userID primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestRevocationStoreOutageIsNotSignOut(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+	s.h.Auth.Revocations = unreachableRevocations{s.h.Auth.Revocations}
+
+	// The session may be fine, so the client shouldn't be told to sign in again
+	w := s.do(request{method: http.MethodGet, path: "/me", token: user.Token})
+	expectError(t, w, http.StatusInternalServerError, utils.CodeInternal)
+}
+
+func TestLogoutAllRevokesOtherSessions(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	// A second device signs in, usually within the same second as the first. Sign-ins in the
+	// same millisecond as the logout count as concurrent with it, so leave a gap.
+	w := s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": user.Email, "password": testPassword}})
+	expectStatus(t, w, http.StatusOK)
+	var other models.AuthResponse
+	decode(t, w, &other)
+	time.Sleep(2 * time.Millisecond)
+
+	expectStatus(t, s.do(request{method: http.MethodPost, path: "/logout/all", token: user.Token}), http.StatusOK)
+
+	for _, token := range []string{user.Token, other.Token} {
+		w := s.do(request{method: http.MethodGet, path: "/me", token: token})
+		expectError(t, w, http.StatusUnauthorized, utils.CodeSessionRevoked)
+	}
+
+	// Signing in again straight away, within the same second, gives a working session
+	w = s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": user.Email, "password": testPassword}})
+	expectStatus(t, w, http.StatusOK)
+	var again models.AuthResponse
+	decode(t, w, &again)
+	expectStatus(t, s.do(request{method: http.MethodGet, path: "/me", token: again.Token}), http.StatusOK)
+}
+
+func TestSigninLockout(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.RateLimit.LockoutThreshold = 3
+	})
+	user := s.signup("ann@example.com")
+
+	for range 3 {
+		w := s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": user.Email, "password": "wrong-password"}})
+		expectError(t, w, http.StatusUnauthorized, utils.CodeInvalidCredentials)
+	}
+
+	// Locked, so even the right password is refused, whatever the case of the email
+	w := s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": "ANN@example.com", "password": testPassword}})
+	expectError(t, w, http.StatusTooManyRequests, utils.CodeRateLimited)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("locked sign-in has no Retry-After header")
+	}
+}
+
+func TestAuthRateLimit(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.RateLimit.AuthLimit = 2
+	})
+
+	body := map[string]string{"email": "nobody@example.com", "password": testPassword}
+	for range 2 {
+		expectStatus(t, s.do(request{method: http.MethodPost, path: "/signin", body: body}), http.StatusUnauthorized)
+	}
+	w := s.do(request{method: http.MethodPost, path: "/signin", body: body})
+	expectError(t, w, http.StatusTooManyRequests, utils.CodeRateLimited)
+}
+
+func TestBindingValidation(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		body  any
+		code  utils.ErrorCode
+		field string
+	}{
+		{"missing field", map[string]string{"email": "ann@example.com", "password": testPassword, "first_name": "Ann"}, utils.CodeValidationFailed, "last_name"},
+		{"bad email", map[string]string{"email": "ann", "password": testPassword, "first_name": "Ann", "last_name": "Lee"}, utils.CodeValidationFailed, "email"},
+		{"short password", map[string]string{"email": "ann@example.com", "password": "123", "first_name": "Ann", "last_name": "Lee"}, utils.CodeValidationFailed, "password"},
+		{"not JSON", "just a string", utils.CodeInvalidJSON, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: http.MethodPost, path: "/signup", body: tt.body})
+			expectStatus(t, w, http.StatusBadRequest)
+
+			var apiErr utils.APIError
+			decode(t, w, &apiErr)
+			if apiErr.Code != tt.code {
+				t.Fatalf("error code = %q, want %q", apiErr.Code, tt.code)
+			}
+			if tt.field != "" && (len(apiErr.Details) == 0 || apiErr.Details[0].Field != tt.field) {
+				t.Fatalf("details = %+v, want an error for %q", apiErr.Details, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bryce-stabenow/grocer-me/events"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+// heartbeatInterval keeps idle event streams from being closed by proxies
+const heartbeatInterval = 25 * time.Second
+
+// HandleListEvents streams changes to a list as Server-Sent Events
+func (h *Handler) HandleListEvents(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Fetch list
+	list, ok := utils.FetchList(w, h.Lists, listID)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Check if user has access
+	if !utils.CheckListAccess(w, list, userID) {
+		return // Error response already sent
+	}
+
+	// Subscribe before writing anything so no event is missed
+	stream, unsubscribe := h.Events.Subscribe(listID, userID)
+	defer unsubscribe()
+
+	// The stream outlives any server write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	utils.SetListETag(w, list)
+	w.WriteHeader(http.StatusOK)
+
+	// Tell the client which version the stream starts from
+	fmt.Fprintf(w, "event: ready\ndata: {\"version\":%d}\n\n", list.Version)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, open := <-stream:
+			if !open {
+				// Dropped for falling behind or for losing access; the client reconnects and reloads
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Version, event.Type, data)
+			if event.Type == events.ListDeleted {
+				rc.Flush()
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
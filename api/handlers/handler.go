@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/events"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/middleware"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+// Check the binding tags of every request body at startup rather than on its first request
+func init() {
+	utils.RegisterBindings(
+		models.SignupRequest{},
+		models.SigninRequest{},
+		models.RefreshTokenRequest{},
+		models.ForgotPasswordRequest{},
+		models.ResetPasswordRequest{},
+		models.UpdateProfileRequest{},
+		models.ChangePasswordRequest{},
+		models.ChangeEmailRequest{},
+		models.DeleteAccountRequest{},
+		models.CreateListRequest{},
+		models.UpdateListRequest{},
+		models.AddListItemRequest{},
+		models.UpdateListItemCheckedRequest{},
+		models.UpdateListItemRequest{},
+		models.UpdateMemberRoleRequest{},
+		models.TransferOwnershipRequest{},
+		models.CreateInviteRequest{},
+		models.JoinListRequest{},
+	)
+}
+
+// Handler serves the API on top of the injected stores
+type Handler struct {
+	Users          store.UserStore
+	Lists          store.ListStore
+	RefreshTokens  store.RefreshTokenStore
+	Invites        store.InviteStore
+	PasswordResets store.PasswordResetStore
+	Mailer         mailer.Mailer
+	Auth           *middleware.Authenticator
+	AuthLimiter    *middleware.RateLimiter // Per client IP on the authentication routes
+	EmailLimiter   *middleware.RateLimiter // Per address on routes that send email
+	Lockout        *middleware.Lockout
+	Events         *events.Broker
+
+	mail sync.WaitGroup // Messages still being sent in the background
+}
+
+// New creates a Handler backed by the given stores that sends email through mail
+func New(stores store.Stores, mail mailer.Mailer) *Handler {
+	return &Handler{
+		Users:          stores.Users,
+		Lists:          stores.Lists,
+		RefreshTokens:  stores.RefreshTokens,
+		Invites:        stores.Invites,
+		PasswordResets: stores.PasswordResets,
+		Mailer:         mail,
+		Auth:           middleware.NewAuthenticator(stores.Revocations),
+		AuthLimiter:    middleware.NewRateLimiter(stores.RateLimits, "auth", config.Current.RateLimit.AuthLimit, config.Current.RateLimit.AuthWindow),
+		EmailLimiter:   middleware.NewRateLimiter(stores.RateLimits, "email", config.Current.RateLimit.EmailLimit, config.Current.RateLimit.EmailWindow),
+		Lockout:        middleware.NewLockout(stores.RateLimits),
+		Events:         events.NewBroker(),
+	}
+}
+
+// Register adds all API routes to the router
+func (h *Handler) Register(router *utils.Router) {
+	api := router.Group("/", middleware.BodyLimit(config.Current.Server.MaxBodyBytes))
+
+	// Public routes - API endpoints
+	api.POST("/token/refresh", h.HandleRefreshToken)
+	api.POST("/logout", h.HandleLogout)
+	api.POST("/lists/share/:id", h.HandleShareList)
+
+	// Public routes a client IP can only call so often
+	limited := api.Group("/", h.withRateLimit)
+	limited.POST("/signup", h.HandleSignup)
+	limited.POST("/signin", h.HandleSignin)
+	limited.POST("/password/forgot", h.HandleForgotPassword)
+	limited.POST("/password/reset", h.HandleResetPassword)
+	limited.GET("/verify-email", h.HandleVerifyEmail)
+
+	// Protected routes (require JWT)
+	protected := api.Group("/", h.withAuth)
+	protected.GET("/me", h.HandleGetMe)
+	protected.PATCH("/me", h.HandleUpdateProfile)
+	protected.POST("/logout/all", h.HandleLogoutAll)
+
+	// Protected routes that check a password or send email are rate limited too
+	sensitive := limited.Group("/", h.withAuth)
+	sensitive.POST("/me/password", h.HandleChangePassword)
+	sensitive.POST("/me/email", h.HandleChangeEmail)
+	sensitive.DELETE("/me", h.HandleDeleteAccount)
+	sensitive.POST("/verify-email/resend", h.HandleResendVerification)
+
+	// List routes
+	lists := protected.Group("/lists")
+	lists.POST("/", h.HandleCreateList)
+	lists.GET("/", h.HandleGetLists)
+	lists.GET("/:id", h.HandleGetList)
+	lists.GET("/:id/events", h.HandleListEvents)
+	lists.PUT("/:id", h.HandleUpdateList)
+	lists.DELETE("/:id", h.HandleDeleteList)
+	lists.POST("/:id/invites", h.HandleCreateInvite)
+	lists.GET("/:id/invites", h.HandleGetInvites)
+	lists.DELETE("/:id/invites/:inviteId", h.HandleRevokeInvite)
+	lists.PUT("/:id/members/:userId", h.HandleUpdateMemberRole)
+	lists.DELETE("/:id/members/:userId", h.HandleRemoveMember)
+	lists.POST("/:id/leave", h.HandleLeaveList)
+	lists.POST("/:id/transfer", h.HandleTransferOwnership)
+	lists.POST("/:id/items", h.HandleAddListItem)
+	lists.PUT("/:id/items/:itemId", h.HandleUpdateListItem)
+	lists.DELETE("/:id/items/:itemId", h.HandleDeleteListItem)
+	lists.PUT("/:id/items/:itemId/checked", h.HandleUpdateListItemChecked)
+}
+
+// withRateLimit limits how often a client IP can call a sensitive route
+func (h *Handler) withRateLimit(handler http.HandlerFunc) http.HandlerFunc {
+	return h.AuthLimiter.ByIP(handler)
+}
+
+// withAuth wraps a handler with JWT authentication middleware
+func (h *Handler) withAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return h.Auth.JWTAuth(handler)
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testPassword is the password every test account signs up with
+const testPassword = "hunter22"
+
+// testServer is the API running on in-memory stores, with outgoing mail captured
+type testServer struct {
+	t      *testing.T
+	h      *Handler
+	router *utils.Router
+	mail   *captureMailer
+}
+
+// newTestServer starts the API on fresh memory stores. configure, if given, adjusts the
+// configuration before the handler is built.
+func newTestServer(t *testing.T, configure ...func(cfg *config.Config)) *testServer {
+	t.Helper()
+
+	cfg := config.Defaults()
+	cfg.Auth.JWTSecret = "test-secret"
+	cfg.Auth.EmailVerificationPolicy = "none"
+	cfg.RateLimit.AuthLimit = 1000
+	cfg.RateLimit.EmailLimit = 1000
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	previous := config.Current
+	config.Current = cfg
+	t.Cleanup(func() { config.Current = previous })
+
+	mail := &captureMailer{sent: make(chan mailer.Message, 100)}
+	h := New(store.NewMemoryStores(), mail)
+	t.Cleanup(h.Events.Close)
+
+	router := utils.NewRouter()
+	h.Register(router)
+
+	return &testServer{t: t, h: h, router: router, mail: mail}
+}
+
+// request describes a call to the API
+type request struct {
+	method  string
+	path    string
+	token   string // Sent as a bearer token if set
+	body    any    // Encoded as JSON if set
+	headers map[string]string
+}
+
+// do sends a request through the router and returns the recorded response
+func (s *testServer) do(req request) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var body bytes.Buffer
+	if req.body != nil {
+		if err := json.NewEncoder(&body).Encode(req.body); err != nil {
+			s.t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	r := httptest.NewRequest(req.method, req.path, &body)
+	if req.body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if req.token != "" {
+		r.Header.Set("Authorization", "Bearer "+req.token)
+	}
+	for name, value := range req.headers {
+		r.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+// testUser is a signed-up account and its tokens
+type testUser struct {
+	ID           primitive.ObjectID
+	Email        string
+	Token        string
+	RefreshToken string
+}
+
+// signup creates an account and returns it signed in
+func (s *testServer) signup(email string) testUser {
+	s.t.Helper()
+
+	w := s.do(request{method: http.MethodPost, path: "/signup", body: map[string]string{
+		"email":      email,
+		"password":   testPassword,
+		"first_name": "Test",
+		"last_name":  "User",
+	}})
+	expectStatus(s.t, w, http.StatusCreated)
+
+	var resp models.AuthResponse
+	decode(s.t, w, &resp)
+	id, err := primitive.ObjectIDFromHex(resp.User.ID)
+	if err != nil {
+		s.t.Fatalf("signup returned user ID %q: %v", resp.User.ID, err)
+	}
+	return testUser{ID: id, Email: email, Token: resp.Token, RefreshToken: resp.RefreshToken}
+}
+
+// createList creates a list owned by the user
+func (s *testServer) createList(owner testUser, name string) models.ListResponse {
+	s.t.Helper()
+
+	w := s.do(request{method: http.MethodPost, path: "/lists", token: owner.Token, body: map[string]string{"name": name}})
+	expectStatus(s.t, w, http.StatusCreated)
+
+	var list models.ListResponse
+	decode(s.t, w, &list)
+	return list
+}
+
+// addItem adds an item to a list and returns the list afterwards
+func (s *testServer) addItem(user testUser, listID, name string) models.ListResponse {
+	s.t.Helper()
+
+	w := s.do(request{method: http.MethodPost, path: "/lists/" + listID + "/items", token: user.Token, body: map[string]string{"name": name}})
+	expectStatus(s.t, w, http.StatusOK)
+
+	var list models.ListResponse
+	decode(s.t, w, &list)
+	return list
+}
+
+// addMember puts the user on the list with the given role, bypassing invites
+func (s *testServer) addMember(listID string, user testUser, role models.Role) {
+	s.t.Helper()
+
+	id, err := primitive.ObjectIDFromHex(listID)
+	if err != nil {
+		s.t.Fatalf("list ID %q: %v", listID, err)
+	}
+	_, err = s.h.Lists.AddMember(context.Background(), id, models.Membership{UserID: user.ID, Role: role, AddedAt: time.Now()})
+	if err != nil {
+		s.t.Fatalf("add member: %v", err)
+	}
+}
+
+// nextMail waits for the next message sent to the address whose subject contains subject,
+// skipping any others
+func (s *testServer) nextMail(to, subject string) mailer.Message {
+	s.t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-s.mail.sent:
+			if msg.To == to && strings.Contains(msg.Subject, subject) {
+				return msg
+			}
+		case <-timeout:
+			s.t.Fatalf("no %q email was sent to %s", subject, to)
+			return mailer.Message{}
+		}
+	}
+}
+
+// captureMailer records every message instead of sending it
+type captureMailer struct {
+	sent chan mailer.Message
+}
+
+// Send records the message
+func (m *captureMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// expectStatus fails the test if the response doesn't have the given status
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, status, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+// expectError fails the test if the response isn't an error with the given status and code
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code utils.ErrorCode) {
+	t.Helper()
+	expectStatus(t, w, status)
+
+	var apiErr utils.APIError
+	decode(t, w, &apiErr)
+	if apiErr.Code != code {
+		t.Fatalf("error code = %q, want %q; message: %s", apiErr.Code, code, apiErr.Message)
+	}
+}
+
+// decode reads the JSON response body into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+}
+
+// linkToken pulls the token query parameter out of the link in an email
+func linkToken(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+
+	_, rest, ok := strings.Cut(msg.Body, "token=")
+	if !ok {
+		t.Fatalf("no token link in email %q", msg.Body)
+	}
+	escaped, _, _ := strings.Cut(rest, "\n")
+	token, err := url.QueryUnescape(strings.TrimSpace(escaped))
+	if err != nil {
+		t.Fatalf("token in email link: %v", err)
+	}
+	return token
+}
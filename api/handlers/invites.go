@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// inviteTokenType marks a JWT as an invite and selects its signing key
+	inviteTokenType = "invite"
+
+	// Defaults for invites that don't say otherwise. The limits are binding tags on the request.
+	defaultInviteTTL     = 7 * 24 * time.Hour
+	defaultInviteMaxUses = 10
+)
+
+// errInvalidInvite is returned when an invite token is malformed, forged or expired
+var errInvalidInvite = errors.New("invalid invite token")
+
+// HandleCreateInvite handles minting a new invite link for a list (admins and the owner)
+func (h *Handler) HandleCreateInvite(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	var req models.CreateInviteRequest
+	if r.ContentLength != 0 {
+		if err := utils.DecodeJSON(r, &req); err != nil {
+			utils.DecodeErrorResponse(w, err)
+			return
+		}
+	}
+
+	// Fill in defaults; the request's binding tags already checked the ranges
+	ttl := defaultInviteTTL
+	if req.ExpiresInHours != nil {
+		ttl = time.Duration(*req.ExpiresInHours) * time.Hour
+	}
+
+	role := models.RoleEditor
+	if req.Role != "" {
+		role = req.Role
+	}
+
+	maxUses := defaultInviteMaxUses
+	if req.MaxUses != nil {
+		maxUses = *req.MaxUses
+	}
+
+	// Fetch list and check the user can manage members
+	list, ok := utils.FetchList(w, h.Lists, listID)
+	if !ok {
+		return // Error response already sent
+	}
+	if !utils.CheckListRole(w, list, userID, models.RoleAdmin) {
+		return // Error response already sent
+	}
+
+	// Only the owner can hand out admin
+	if role == models.RoleAdmin && !utils.CheckListOwnership(w, list, userID) {
+		return // Error response already sent
+	}
+
+	// Unverified accounts can't invite others, depending on the verification policy
+	if !h.checkEmailVerified(w, userID, policyInvite) {
+		return // Error response already sent
+	}
+
+	now := time.Now()
+	invite := &models.Invite{
+		ID:        primitive.NewObjectID(),
+		ListID:    listID,
+		CreatedBy: userID,
+		Role:      role,
+		MaxUses:   maxUses,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	token, err := generateInviteToken(invite)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate invite token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.Invites.Create(ctx, invite); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to create invite")
+		return
+	}
+
+	response := inviteToResponse(invite)
+	response.Token = token
+	utils.JSONResponse(w, http.StatusCreated, response)
+}
+
+// HandleGetInvites handles listing a list's active invites (admins and the owner)
+func (h *Handler) HandleGetInvites(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Fetch list and check the user can manage members
+	list, ok := utils.FetchList(w, h.Lists, listID)
+	if !ok {
+		return // Error response already sent
+	}
+	if !utils.CheckListRole(w, list, userID, models.RoleAdmin) {
+		return // Error response already sent
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invites, err := h.Invites.FindActiveForList(ctx, listID, time.Now())
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to fetch invites")
+		return
+	}
+
+	responses := make([]models.InviteResponse, len(invites))
+	for i := range invites {
+		responses[i] = inviteToResponse(&invites[i])
+	}
+
+	utils.JSONResponse(w, http.StatusOK, responses)
+}
+
+// HandleRevokeInvite handles revoking an invite so its link stops working (admins and the owner)
+func (h *Handler) HandleRevokeInvite(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	inviteID, err := primitive.ObjectIDFromHex(utils.GetPathParam(r, "inviteId"))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidID, "Invalid invite ID format")
+		return
+	}
+
+	// Fetch list and check the user can manage members
+	list, ok := utils.FetchList(w, h.Lists, listID)
+	if !ok {
+		return // Error response already sent
+	}
+	if !utils.CheckListRole(w, list, userID, models.RoleAdmin) {
+		return // Error response already sent
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Make sure the invite belongs to this list
+	invite, err := h.Invites.FindByID(ctx, inviteID)
+	if err != nil || invite.ListID != listID {
+		if err == nil || errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeInviteNotFound, "Invite not found")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to fetch invite")
+		return
+	}
+
+	if err := h.Invites.Revoke(ctx, inviteID, time.Now()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeInviteNotFound, "Invite not found")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke invite")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "Invite revoked successfully"})
+}
+
+// inviteTokenFromRequest reads the invite token from the request body or the ?token= query parameter
+func inviteTokenFromRequest(r *http.Request) (string, error) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token, nil
+	}
+	if r.ContentLength == 0 {
+		return "", nil
+	}
+
+	var req models.JoinListRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		return "", err
+	}
+	return req.Token, nil
+}
+
+// generateInviteToken signs a token that points at the invite and expires with it
+func generateInviteToken(invite *models.Invite) (string, error) {
+	claims := jwt.MapClaims{
+		"typ":       inviteTokenType,
+		"invite_id": invite.ID.Hex(),
+		"list_id":   invite.ListID.Hex(),
+		"iat":       invite.CreatedAt.Unix(),
+		"exp":       invite.ExpiresAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.Current.Auth.SigningKey(inviteTokenType))
+}
+
+// parseInviteToken verifies an invite token and returns the invite and list it points at
+func parseInviteToken(tokenString string) (inviteID, listID primitive.ObjectID, err error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return config.Current.Auth.SigningKey(inviteTokenType), nil
+	})
+	if err != nil || !token.Valid {
+		return inviteID, listID, errInvalidInvite
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != inviteTokenType {
+		return inviteID, listID, errInvalidInvite
+	}
+
+	inviteHex, _ := claims["invite_id"].(string)
+	listHex, _ := claims["list_id"].(string)
+	if inviteID, err = primitive.ObjectIDFromHex(inviteHex); err != nil {
+		return inviteID, listID, errInvalidInvite
+	}
+	if listID, err = primitive.ObjectIDFromHex(listHex); err != nil {
+		return inviteID, listID, errInvalidInvite
+	}
+	return inviteID, listID, nil
+}
+
+// inviteToResponse converts an Invite model to InviteResponse
+func inviteToResponse(invite *models.Invite) models.InviteResponse {
+	return models.InviteResponse{
+		ID:        invite.ID.Hex(),
+		ListID:    invite.ListID.Hex(),
+		CreatedBy: invite.CreatedBy.Hex(),
+		Role:      invite.Role,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		ExpiresAt: invite.ExpiresAt,
+		CreatedAt: invite.CreatedAt,
+	}
+}
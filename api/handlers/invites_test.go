@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createInvite mints an invite for the list and returns it with its token
+func (s *testServer) createInvite(admin testUser, listID string, body map[string]any) models.InviteResponse {
+	s.t.Helper()
+
+	w := s.do(request{method: http.MethodPost, path: "/lists/" + listID + "/invites", token: admin.Token, body: body})
+	expectStatus(s.t, w, http.StatusCreated)
+
+	var invite models.InviteResponse
+	decode(s.t, w, &invite)
+	return invite
+}
+
+// join redeems an invite token for the list. An empty token sends no body.
+func (s *testServer) join(user testUser, listID, token string) *httptest.ResponseRecorder {
+	req := request{method: http.MethodPost, path: "/lists/share/" + listID, token: user.Token}
+	if token != "" {
+		req.body = map[string]string{"token": token}
+	}
+	return s.do(req)
+}
+
+func TestJoinWithInvite(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	guest := s.signup("guest@example.com")
+	list := s.createList(owner, "Groceries")
+
+	invite := s.createInvite(owner, list.ID, map[string]any{"role": "viewer"})
+
+	w := s.join(guest, list.ID, invite.Token)
+	expectStatus(t, w, http.StatusOK)
+	var joined models.ListResponse
+	decode(t, w, &joined)
+	if joined.Role != models.RoleViewer {
+		t.Fatalf("joined with role %q, want %q", joined.Role, models.RoleViewer)
+	}
+
+	// Joining again is a no-op that doesn't use up the invite
+	expectStatus(t, s.join(guest, list.ID, invite.Token), http.StatusOK)
+	w = s.do(request{method: http.MethodGet, path: "/lists/" + list.ID + "/invites", token: owner.Token})
+	var invites []models.InviteResponse
+	decode(t, w, &invites)
+	if len(invites) != 1 || invites[0].Uses != 1 {
+		t.Fatalf("invites = %+v, want one with one use", invites)
+	}
+}
+
+func TestJoinRejectsBadInvites(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	list := s.createList(owner, "Groceries")
+	other := s.createList(owner, "Hardware")
+
+	single := s.createInvite(owner, list.ID, map[string]any{"max_uses": 1})
+	expectStatus(t, s.join(s.signup("first@example.com"), list.ID, single.Token), http.StatusOK)
+
+	revoked := s.createInvite(owner, list.ID, nil)
+	w := s.do(request{method: http.MethodDelete, path: "/lists/" + list.ID + "/invites/" + revoked.ID, token: owner.Token})
+	expectStatus(t, w, http.StatusOK)
+
+	wrongList := s.createInvite(owner, other.ID, nil)
+
+	// An invite whose link and record have both expired
+	listID, _ := primitive.ObjectIDFromHex(list.ID)
+	created := time.Now().Add(-2 * time.Hour)
+	expiredInvite := &models.Invite{
+		ID:        primitive.NewObjectID(),
+		ListID:    listID,
+		CreatedBy: owner.ID,
+		Role:      models.RoleEditor,
+		MaxUses:   10,
+		ExpiresAt: created.Add(time.Hour),
+		CreatedAt: created,
+	}
+	if err := s.h.Invites.Create(context.Background(), expiredInvite); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := generateInviteToken(expiredInvite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		code  utils.ErrorCode
+	}{
+		{"no invite", "", utils.CodeInviteRequired},
+		{"forged", "not-a-jwt", utils.CodeInvalidInvite},
+		{"used up", single.Token, utils.CodeInvalidInvite},
+		{"revoked", revoked.Token, utils.CodeInvalidInvite},
+		{"expired", expired, utils.CodeInvalidInvite},
+		{"for another list", wrongList.Token, utils.CodeInvalidInvite},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guest := s.signup(fmt.Sprintf("guest%d@example.com", i))
+			w := s.join(guest, list.ID, tt.token)
+			expectError(t, w, http.StatusForbidden, tt.code)
+		})
+	}
+}
+
+// joinRacingLists is a ListStore whose AddMember runs a hook first, to stand in for a
+// request that changes the list between the handler's checks and its write
+type joinRacingLists struct {
+	store.ListStore
+	beforeAddMember func(id primitive.ObjectID)
+}
+
+func (l *joinRacingLists) AddMember(ctx context.Context, id primitive.ObjectID, member models.Membership) (*models.List, error) {
+	l.beforeAddMember(id)
+	return l.ListStore.AddMember(ctx, id, member)
+}
+
+func TestJoinGivesBackUnusedInvite(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	guest := s.signup("guest@example.com")
+	list := s.createList(owner, "Groceries")
+	invite := s.createInvite(owner, list.ID, map[string]any{"role": "viewer", "max_uses": 1})
+
+	// The guest joins through another request while this one is redeeming the invite
+	lists := s.h.Lists
+	s.h.Lists = &joinRacingLists{ListStore: lists, beforeAddMember: func(id primitive.ObjectID) {
+		lists.AddMember(context.Background(), id, models.Membership{UserID: guest.ID, Role: models.RoleViewer, AddedAt: time.Now()})
+	}}
+
+	w := s.join(guest, list.ID, invite.Token)
+	expectStatus(t, w, http.StatusOK)
+	var joined models.ListResponse
+	decode(t, w, &joined)
+	if joined.Role != models.RoleViewer {
+		t.Fatalf("joined with role %q, want %q", joined.Role, models.RoleViewer)
+	}
+
+	// The losing request didn't add anyone, so the single use is still there
+	s.h.Lists = lists
+	expectStatus(t, s.join(s.signup("other@example.com"), list.ID, invite.Token), http.StatusOK)
+}
@@ -2,21 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
-	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/events"
 	"bryce-stabenow/grocer-me/middleware"
 	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
 	"bryce-stabenow/grocer-me/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // HandleCreateList handles creating a new list
-func HandleCreateList(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleCreateList(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -26,12 +27,11 @@ func HandleCreateList(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req models.CreateListRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
 	// Create list
-	collection := config.DB.Collection("lists")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -42,77 +42,56 @@ func HandleCreateList(w http.ResponseWriter, r *http.Request) {
 		Name:        req.Name,
 		Description: req.Description,
 		Items:       []models.ListItem{},
-		SharedWith:  []primitive.ObjectID{},
+		Members:     []models.Membership{},
+		Version:     1,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 
-	result, err := collection.InsertOne(ctx, list)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create list")
+	if err := h.Lists.Create(ctx, &list); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to create list")
 		return
 	}
 
 	// Fetch the created list to return
-	var createdList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": result.InsertedID}).Decode(&createdList)
+	createdList, err := h.Lists.FindByID(ctx, list.ID)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve created list")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to retrieve created list")
 		return
 	}
 
-	// Convert to response format
-	response := listToResponse(&createdList)
-	utils.JSONResponse(w, http.StatusCreated, response)
+	h.writeListResponse(w, http.StatusCreated, createdList, userID)
 }
 
 // HandleGetLists handles getting all lists for the authenticated user
-func HandleGetLists(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleGetLists(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
 		return // Error response already sent
 	}
 
-	// Find lists where user is owner or in shared_with array
-	collection := config.DB.Collection("lists")
+	// Find lists where user is owner or a member
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"$or": []bson.M{
-			{"user_id": userID},
-			{"shared_with": userID},
-		},
-	}
-
-	// Sort by created_at descending
-	opts := options.Find().SetSort(bson.M{"created_at": -1})
-
-	cursor, err := collection.Find(ctx, filter, opts)
+	lists, err := h.Lists.FindForUser(ctx, userID)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch lists")
-		return
-	}
-	defer cursor.Close(ctx)
-
-	var lists []models.List
-	if err = cursor.All(ctx, &lists); err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to decode lists")
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to fetch lists")
 		return
 	}
 
 	// Convert to response format
 	responses := make([]models.ListResponse, len(lists))
 	for i, list := range lists {
-		responses[i] = listToResponse(&list)
+		responses[i] = h.listToResponse(&list, userID)
 	}
 
 	utils.JSONResponse(w, http.StatusOK, responses)
 }
 
 // HandleGetList handles getting a single list by ID
-func HandleGetList(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleGetList(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -126,7 +105,7 @@ func HandleGetList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch list
-	list, ok := utils.FetchList(w, listID)
+	list, ok := utils.FetchList(w, h.Lists, listID)
 	if !ok {
 		return // Error response already sent
 	}
@@ -136,13 +115,11 @@ func HandleGetList(w http.ResponseWriter, r *http.Request) {
 		return // Error response already sent
 	}
 
-	// Convert to response format
-	response := listToResponse(list)
-	utils.JSONResponse(w, http.StatusOK, response)
+	h.writeListResponse(w, http.StatusOK, list, userID)
 }
 
 // HandleUpdateList handles updating a list
-func HandleUpdateList(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -158,62 +135,53 @@ func HandleUpdateList(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req models.UpdateListRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
-	// Fetch list and verify access
-	list, ok := utils.FetchList(w, listID)
-	if !ok {
-		return // Error response already sent
-	}
-
-	// Check if user has access
-	if !utils.CheckListAccess(w, list, userID) {
-		return // Error response already sent
-	}
-
-	// Build update document
-	collection := config.DB.Collection("lists")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	update := bson.M{
-		"updated_at": time.Now(),
-	}
+	// Build update
+	var update store.ListUpdate
 	if req.Name != "" {
-		update["name"] = req.Name
+		update.Name = &req.Name
 	}
 	if req.Description != "" {
-		update["description"] = req.Description
+		update.Description = &req.Description
 	}
 
 	// Update the list
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": listID},
-		bson.M{"$set": update},
-	)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to update list")
-		return
+	list, updatedList, ok := h.writeList(w, r, listID, "Failed to update list", func(list *models.List) bool {
+		// Only admins and the owner can edit the list itself
+		if !utils.CheckListRole(w, list, userID, models.RoleAdmin) {
+			return false // Error response already sent
+		}
+
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.Update(ctx, listID, version, update)
+	})
+	if !ok {
+		return // Error response already sent
 	}
 
-	// Fetch the updated list to return
-	var updatedList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&updatedList)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve updated list")
-		return
+	// Let other members know
+	eventType := events.ListUpdated
+	if update.Name != nil && *update.Name != list.Name {
+		eventType = events.ListRenamed
 	}
+	h.Events.Publish(listID, events.Event{
+		Type:        eventType,
+		Version:     updatedList.Version,
+		ActorID:     userID.Hex(),
+		Name:        updatedList.Name,
+		Description: updatedList.Description,
+	})
 
-	// Convert to response format
-	response := listToResponse(&updatedList)
-	utils.JSONResponse(w, http.StatusOK, response)
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
 }
 
 // HandleAddListItem handles adding an item to a list
-func HandleAddListItem(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleAddListItem(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -229,7 +197,7 @@ func HandleAddListItem(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req models.AddListItemRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
@@ -239,63 +207,47 @@ func HandleAddListItem(w http.ResponseWriter, r *http.Request) {
 		quantity = 1
 	}
 
-	// Fetch list and verify access
-	list, ok := utils.FetchList(w, listID)
-	if !ok {
-		return // Error response already sent
-	}
-
-	// Check if user has access
-	if !utils.CheckListAccess(w, list, userID) {
-		return // Error response already sent
-	}
-
 	// Create new item
-	collection := config.DB.Collection("lists")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	now := time.Now()
 	newItem := models.ListItem{
+		ID:       primitive.NewObjectID(),
 		Name:     req.Name,
 		Quantity: quantity,
 		Checked:  false,
 		Details:  req.Details,
 		AddedBy:  userID,
-		AddedAt:  now,
+		AddedAt:  time.Now(),
 	}
 
-	// Add item to list and update updated_at
-	update := bson.M{
-		"$push": bson.M{"items": newItem},
-		"$set":  bson.M{"updated_at": now},
-	}
+	// Add item to list
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to add item to list", func(list *models.List) bool {
+		// Viewers can't change items
+		if !utils.CheckListRole(w, list, userID, models.RoleEditor) {
+			return false // Error response already sent
+		}
 
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": listID},
-		update,
-	)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to add item to list")
-		return
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.AddItem(ctx, listID, version, newItem)
+	})
+	if !ok {
+		return // Error response already sent
 	}
 
-	// Fetch the updated list to return
-	var updatedList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&updatedList)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve updated list")
-		return
-	}
+	// Let other members know
+	h.Events.Publish(listID, events.Event{
+		Type:    events.ItemAdded,
+		Version: updatedList.Version,
+		ActorID: userID.Hex(),
+		ItemID:  newItem.ID.Hex(),
+		Item:    &newItem,
+	})
 
-	// Convert to response format
-	response := listToResponse(&updatedList)
-	utils.JSONResponse(w, http.StatusOK, response)
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
 }
 
 // HandleUpdateListItemChecked handles updating an item's checked state
-func HandleUpdateListItemChecked(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleUpdateListItemChecked(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -308,77 +260,50 @@ func HandleUpdateListItemChecked(w http.ResponseWriter, r *http.Request) {
 		return // Error response already sent
 	}
 
-	// Parse request body
-	var req models.UpdateListItemCheckedRequest
-	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	// Fetch list and verify access
-	list, ok := utils.FetchList(w, listID)
+	// Get and validate item ID
+	itemID, ok := utils.GetAndValidateItemID(w, r)
 	if !ok {
 		return // Error response already sent
 	}
 
-	// Check if user has access
-	if !utils.CheckListAccess(w, list, userID) {
-		return // Error response already sent
-	}
-
-	// Validate index
-	if req.Index == nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Index is required")
-		return
-	}
-
-	index := *req.Index
-	if index < 0 || index >= len(list.Items) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid item index")
+	// Parse request body
+	var req models.UpdateListItemCheckedRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
 	// Update the item's checked state
-	collection := config.DB.Collection("lists")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	update := store.ItemUpdate{Checked: &req.Checked}
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to update item", func(list *models.List) bool {
+		// Viewers can't change items
+		if !utils.CheckListRole(w, list, userID, models.RoleEditor) {
+			return false // Error response already sent
+		}
 
-	now := time.Now()
-	
-	// Update the item in the slice
-	list.Items[index].Checked = req.Checked
-
-	// Update the entire items array and updated_at in the database
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": listID},
-		bson.M{
-			"$set": bson.M{
-				"items":      list.Items,
-				"updated_at": now,
-			},
-		},
-	)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to update item")
-		return
-	}
+		// Reject the write if the client is working from a stale version
+		if !utils.CheckIfMatch(w, r, list) {
+			return false // Error response already sent
+		}
 
-	// Fetch the updated list to return
-	var updatedList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&updatedList)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve updated list")
-		return
+		// Make sure the item exists
+		_, ok := utils.FindListItem(w, list, itemID)
+		return ok
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.UpdateItem(ctx, listID, itemID, version, update)
+	})
+	if !ok {
+		return // Error response already sent
 	}
 
-	// Convert to response format
-	response := listToResponse(&updatedList)
-	utils.JSONResponse(w, http.StatusOK, response)
+	// Let other members know
+	h.publishItemEvent(updatedList, itemID, userID, events.ItemChecked)
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
 }
 
 // HandleUpdateListItem handles updating an item's name, details, and quantity
-func HandleUpdateListItem(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleUpdateListItem(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -391,92 +316,62 @@ func HandleUpdateListItem(w http.ResponseWriter, r *http.Request) {
 		return // Error response already sent
 	}
 
-	// Parse request body
-	var req models.UpdateListItemRequest
-	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	// Fetch list and verify access
-	list, ok := utils.FetchList(w, listID)
+	// Get and validate item ID
+	itemID, ok := utils.GetAndValidateItemID(w, r)
 	if !ok {
 		return // Error response already sent
 	}
 
-	// Check if user has access
-	if !utils.CheckListAccess(w, list, userID) {
-		return // Error response already sent
-	}
-
-	// Validate index
-	if req.Index == nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Index is required")
-		return
-	}
-
-	index := *req.Index
-	if index < 0 || index >= len(list.Items) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid item index")
-		return
-	}
-
-	// Validate details length if provided
-	if req.Details != nil && len(*req.Details) > 512 {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Details must be 512 characters or less")
+	// Parse request body
+	var req models.UpdateListItemRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
 		return
 	}
 
-	// Update the item's fields
-	collection := config.DB.Collection("lists")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	now := time.Now()
-	
 	// Update fields if provided
+	var update store.ItemUpdate
 	if req.Name != "" {
-		list.Items[index].Name = req.Name
+		update.Name = &req.Name
 	}
 	if req.Quantity != nil && *req.Quantity > 0 {
-		list.Items[index].Quantity = *req.Quantity
+		update.Quantity = req.Quantity
 	}
 	if req.Details != nil {
 		// Allow empty string to clear the details field
-		list.Items[index].Details = *req.Details
-	}
-
-	// Update the entire items array and updated_at in the database
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": listID},
-		bson.M{
-			"$set": bson.M{
-				"items":      list.Items,
-				"updated_at": now,
-			},
-		},
-	)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to update item")
-		return
+		update.Details = req.Details
 	}
 
-	// Fetch the updated list to return
-	var updatedList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&updatedList)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve updated list")
-		return
+	// Update the item's fields
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to update item", func(list *models.List) bool {
+		// Viewers can't change items
+		if !utils.CheckListRole(w, list, userID, models.RoleEditor) {
+			return false // Error response already sent
+		}
+
+		// Reject the write if the client is working from a stale version
+		if !utils.CheckIfMatch(w, r, list) {
+			return false // Error response already sent
+		}
+
+		// Make sure the item exists
+		_, ok := utils.FindListItem(w, list, itemID)
+		return ok
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.UpdateItem(ctx, listID, itemID, version, update)
+	})
+	if !ok {
+		return // Error response already sent
 	}
 
-	// Convert to response format
-	response := listToResponse(&updatedList)
-	utils.JSONResponse(w, http.StatusOK, response)
+	// Let other members know
+	h.publishItemEvent(updatedList, itemID, userID, events.ItemUpdated)
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
 }
 
 // HandleDeleteListItem handles deleting an item from a list
-func HandleDeleteListItem(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleDeleteListItem(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -489,77 +384,47 @@ func HandleDeleteListItem(w http.ResponseWriter, r *http.Request) {
 		return // Error response already sent
 	}
 
-	// Parse request body
-	var req models.DeleteListItemRequest
-	if err := utils.DecodeJSON(r, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	// Fetch list and verify access
-	list, ok := utils.FetchList(w, listID)
+	// Get and validate item ID
+	itemID, ok := utils.GetAndValidateItemID(w, r)
 	if !ok {
 		return // Error response already sent
 	}
 
-	// Check if user has access
-	if !utils.CheckListAccess(w, list, userID) {
-		return // Error response already sent
-	}
-
-	// Validate index
-	if req.Index == nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Index is required")
-		return
-	}
-
-	index := *req.Index
-	if index < 0 || index >= len(list.Items) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid item index")
-		return
-	}
-
-	// Remove the item from the slice
-	collection := config.DB.Collection("lists")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Remove the item from the list
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to delete item", func(list *models.List) bool {
+		// Viewers can't change items
+		if !utils.CheckListRole(w, list, userID, models.RoleEditor) {
+			return false // Error response already sent
+		}
 
-	updatedItems := make([]models.ListItem, 0, len(list.Items)-1)
-	updatedItems = append(updatedItems, list.Items[:index]...)
-	updatedItems = append(updatedItems, list.Items[index+1:]...)
+		// Reject the write if the client is working from a stale version
+		if !utils.CheckIfMatch(w, r, list) {
+			return false // Error response already sent
+		}
 
-	// Update the items array and updated_at in the database
-	now := time.Now()
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"_id": listID},
-		bson.M{
-			"$set": bson.M{
-				"items":      updatedItems,
-				"updated_at": now,
-			},
-		},
-	)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete item")
-		return
+		// Make sure the item exists
+		_, ok := utils.FindListItem(w, list, itemID)
+		return ok
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.DeleteItem(ctx, listID, itemID, version)
+	})
+	if !ok {
+		return // Error response already sent
 	}
 
-	// Fetch the updated list to return
-	var updatedList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&updatedList)
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve updated list")
-		return
-	}
+	// Let other members know
+	h.Events.Publish(listID, events.Event{
+		Type:    events.ItemDeleted,
+		Version: updatedList.Version,
+		ActorID: userID.Hex(),
+		ItemID:  itemID.Hex(),
+	})
 
-	// Convert to response format
-	response := listToResponse(&updatedList)
-	utils.JSONResponse(w, http.StatusOK, response)
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
 }
 
 // HandleDeleteList handles deleting a list
-func HandleDeleteList(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleDeleteList(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated user ID
 	userID, ok := utils.GetAuthenticatedUser(w, r)
 	if !ok {
@@ -572,44 +437,50 @@ func HandleDeleteList(w http.ResponseWriter, r *http.Request) {
 		return // Error response already sent
 	}
 
-	// Fetch list and verify ownership
-	list, ok := utils.FetchList(w, listID)
-	if !ok {
-		return // Error response already sent
-	}
+	// Delete the list
+	list, _, ok := h.writeList(w, r, listID, "Failed to delete list", func(list *models.List) bool {
+		// Only the owner can delete the list
+		if !utils.CheckListOwnership(w, list, userID) {
+			return false // Error response already sent
+		}
 
-	// Only the owner can delete the list
-	if !utils.CheckListOwnership(w, list, userID) {
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return nil, h.Lists.Delete(ctx, listID, version)
+	})
+	if !ok {
 		return // Error response already sent
 	}
 
-	// Delete the list
-	collection := config.DB.Collection("lists")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	_, err := collection.DeleteOne(ctx, bson.M{"_id": listID})
-	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete list")
-		return
-	}
+	// Let other members know; their streams close after this event
+	h.Events.Publish(listID, events.Event{
+		Type:    events.ListDeleted,
+		Version: list.Version + 1,
+		ActorID: userID.Hex(),
+	})
 
 	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "List deleted successfully"})
 }
 
-// HandleShareList handles adding the current user to a list's shared_with array
+// HandleShareList handles adding the current user to a list's members with the invite's role.
+// The caller must present a valid invite token for the list, in the body or as ?token=.
 // This endpoint is public but requires authentication (checked internally)
-func HandleShareList(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleShareList(w http.ResponseWriter, r *http.Request) {
 	// Try to extract user ID from JWT (manual check for this public endpoint)
-	userIDStr, err := middleware.ExtractUserID(r)
+	userIDStr, err := h.Auth.ExtractUserID(r)
+	if errors.Is(err, middleware.ErrRevocationCheck) {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to check session")
+		return
+	}
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusUnauthorized, "Authentication required. Please sign in to join this list.")
+		utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeAuthRequired, "Authentication required. Please sign in to join this list.")
 		return
 	}
 
 	userID, err := primitive.ObjectIDFromHex(userIDStr)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid user ID format")
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidID, "Invalid user ID format")
 		return
 	}
 
@@ -619,112 +490,219 @@ func HandleShareList(w http.ResponseWriter, r *http.Request) {
 		return // Error response already sent
 	}
 
+	// Verify the invite token was issued for this list
+	rawToken, err := inviteTokenFromRequest(r)
+	if err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+	if rawToken == "" {
+		utils.ErrorResponse(w, http.StatusForbidden, utils.CodeInviteRequired, "An invite is required to join this list")
+		return
+	}
+	inviteID, inviteListID, err := parseInviteToken(rawToken)
+	if err != nil || inviteListID != listID {
+		utils.ErrorResponse(w, http.StatusForbidden, utils.CodeInvalidInvite, "This invite link is invalid or has expired")
+		return
+	}
+
 	// Fetch list
-	list, ok := utils.FetchList(w, listID)
+	list, ok := utils.FetchList(w, h.Lists, listID)
 	if !ok {
 		return // Error response already sent
 	}
 
 	// Check if user is already the owner
 	if list.UserID == userID {
-		utils.ErrorResponse(w, http.StatusBadRequest, "You are already the owner of this list")
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeAlreadyOwner, "You are already the owner of this list")
 		return
 	}
 
-	// Check if user is already in shared_with array
-	alreadyShared := false
-	for _, sharedUserID := range list.SharedWith {
-		if sharedUserID == userID {
-			alreadyShared = true
-			break
-		}
+	// Check if user is already a member
+	if list.RoleOf(userID) != "" {
+		// User is already a member, return the list anyway (idempotent) without using up the invite
+		h.writeListResponse(w, http.StatusOK, list, userID)
+		return
 	}
 
-	if alreadyShared {
-		// User is already shared, return the list anyway (idempotent)
-		response := listToResponse(list)
-		utils.JSONResponse(w, http.StatusOK, response)
-		return
+	// Unverified accounts can't join shared lists, depending on the verification policy
+	if !h.checkEmailVerified(w, userID, policyShare) {
+		return // Error response already sent
 	}
 
-	// Add user to shared_with array
-	collection := config.DB.Collection("lists")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	now := time.Now()
-	_, err = collection.UpdateOne(
-		ctx,
-		bson.M{"_id": listID},
-		bson.M{
-			"$addToSet": bson.M{"shared_with": userID},
-			"$set":      bson.M{"updated_at": now},
-		},
-	)
+	// Use up one redemption; fails if the invite was revoked, expired or exhausted
+	invite, err := h.Invites.Consume(ctx, inviteID, time.Now())
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to add user to shared list")
+		if errors.Is(err, store.ErrTokenUsed) {
+			utils.ErrorResponse(w, http.StatusForbidden, utils.CodeInvalidInvite, "This invite link is invalid or has expired")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to redeem invite")
 		return
 	}
 
-	// Fetch the updated list to return
-	var updatedList models.List
-	err = collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&updatedList)
+	// Someone who left or was removed needs an invite created since then to come back
+	if removedAt, ok := list.Removed[userID.Hex()]; ok && !invite.CreatedAt.After(removedAt) {
+		if err := h.Invites.Release(ctx, inviteID); err != nil {
+			log.Printf("Failed to release use of invite %s: %v", inviteID.Hex(), err)
+		}
+		utils.ErrorResponse(w, http.StatusForbidden, utils.CodeInvalidInvite, "This invite was created before you left the list. Please ask for a new one.")
+		return
+	}
+
+	// Invites created before roles existed grant edit access, as sharing always did
+	role := invite.Role
+	if !role.Valid() {
+		role = models.RoleEditor
+	}
+
+	// Add user to the list's members
+	updatedList, err := h.Lists.AddMember(ctx, listID, models.Membership{
+		UserID:  userID,
+		Role:    role,
+		AddedAt: time.Now(),
+	})
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve updated list")
+		// The user didn't join through this invite, so give the use back
+		if releaseErr := h.Invites.Release(ctx, inviteID); releaseErr != nil {
+			log.Printf("Failed to release use of invite %s: %v", inviteID.Hex(), releaseErr)
+		}
+
+		if errors.Is(err, store.ErrDuplicate) {
+			// Joined concurrently through another request, so return the list as it is now
+			current, ok := utils.FetchList(w, h.Lists, listID)
+			if !ok {
+				return // Error response already sent
+			}
+			h.writeListResponse(w, http.StatusOK, current, userID)
+			return
+		}
+		writeListStoreError(w, err, "Failed to add user to shared list")
 		return
 	}
 
-	// Convert to response format
-	response := listToResponse(&updatedList)
-	utils.JSONResponse(w, http.StatusOK, response)
+	// Let other members know
+	h.Events.Publish(listID, events.Event{
+		Type:     events.MemberAdded,
+		Version:  updatedList.Version,
+		ActorID:  userID.Hex(),
+		MemberID: userID.Hex(),
+		Role:     string(role),
+	})
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
 }
 
-// listToResponse converts a List model to ListResponse
-func listToResponse(list *models.List) models.ListResponse {
-	// Fetch user emails for shared_with users
-	sharedWith := make([]models.SharedUser, 0, len(list.SharedWith))
-	if len(list.SharedWith) > 0 {
-		userCollection := config.DB.Collection("users")
+// publishItemEvent notifies subscribers about a change to a single item
+func (h *Handler) publishItemEvent(list *models.List, itemID, actorID primitive.ObjectID, eventType string) {
+	event := events.Event{
+		Type:    eventType,
+		Version: list.Version,
+		ActorID: actorID.Hex(),
+		ItemID:  itemID.Hex(),
+	}
+	for _, item := range list.Items {
+		if item.ID == itemID {
+			event.Item = &item
+			break
+		}
+	}
+	h.Events.Publish(list.ID, event)
+}
+
+// maxListWriteAttempts bounds how often a write is retried after losing a version race
+const maxListWriteAttempts = 3
+
+// writeList fetches a list, authorizes a write with check and applies it with write, pinned
+// to the version that was checked so the list can't change in between. Losing that race is a
+// 412 if the client's If-Match named a version; otherwise the list is fetched and checked again.
+// check sends its own error responses. Returns the list as checked and as written.
+func (h *Handler) writeList(
+	w http.ResponseWriter,
+	r *http.Request,
+	listID primitive.ObjectID,
+	message string,
+	check func(list *models.List) bool,
+	write func(ctx context.Context, version int64) (*models.List, error),
+) (*models.List, *models.List, bool) {
+	for attempt := 1; ; attempt++ {
+		list, ok := utils.FetchList(w, h.Lists, listID)
+		if !ok {
+			return nil, nil, false // Error response already sent
+		}
+		if !check(list) {
+			return nil, nil, false // Error response already sent
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		updatedList, err := write(ctx, list.Version)
+		cancel()
+		if err == nil {
+			return list, updatedList, true
+		}
+
+		if errors.Is(err, store.ErrVersionConflict) && !utils.IfMatchPinned(r) && attempt < maxListWriteAttempts {
+			continue
+		}
+		writeListStoreError(w, err, message)
+		return nil, nil, false
+	}
+}
+
+// writeListStoreError maps a list store error to an HTTP error response
+func writeListStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, utils.CodeListNotFound, "List not found")
+	case errors.Is(err, store.ErrItemNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, utils.CodeItemNotFound, "Item not found")
+	case errors.Is(err, store.ErrMemberNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, utils.CodeMemberNotFound, "Member not found")
+	case errors.Is(err, store.ErrVersionConflict):
+		utils.PreconditionFailed(w)
+	default:
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, message)
+	}
+}
+
+// writeListResponse sends a list, as seen by the given user, along with its ETag header
+func (h *Handler) writeListResponse(w http.ResponseWriter, statusCode int, list *models.List, userID primitive.ObjectID) {
+	utils.SetListETag(w, list)
+	utils.JSONResponse(w, statusCode, h.listToResponse(list, userID))
+}
+
+// listToResponse converts a List model to ListResponse, including the user's role on it
+func (h *Handler) listToResponse(list *models.List, userID primitive.ObjectID) models.ListResponse {
+	// Fetch user emails for members
+	sharedWith := make([]models.SharedUser, 0, len(list.Members))
+	if len(list.Members) > 0 {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
+		memberIDs := make([]primitive.ObjectID, len(list.Members))
+		for i, member := range list.Members {
+			memberIDs[i] = member.UserID
+		}
+
 		// Fetch all users in a single query
-		cursor, err := userCollection.Find(ctx, bson.M{"_id": bson.M{"$in": list.SharedWith}})
-		if err == nil {
-			defer cursor.Close(ctx)
-			
-			// Create a map of user ID to email for quick lookup
-			userMap := make(map[primitive.ObjectID]string)
-			var user models.User
-			for cursor.Next(ctx) {
-				if err := cursor.Decode(&user); err == nil {
-					userMap[user.ID] = user.Email
-				}
+		// If the query fails, members are still included but with empty emails
+		userMap := make(map[primitive.ObjectID]string)
+		if users, err := h.Users.FindByIDs(ctx, memberIDs); err == nil {
+			for _, user := range users {
+				userMap[user.ID] = user.Email
 			}
+		}
 
-			// Build sharedWith array maintaining the original order
-			for _, userID := range list.SharedWith {
-				if email, exists := userMap[userID]; exists {
-					sharedWith = append(sharedWith, models.SharedUser{
-						ID:    userID.Hex(),
-						Email: email,
-					})
-				} else {
-					// If user not found, still include the ID but with empty email
-					sharedWith = append(sharedWith, models.SharedUser{
-						ID:    userID.Hex(),
-						Email: "",
-					})
-				}
-			}
-		} else {
-			// If query fails, fall back to just IDs
-			for _, userID := range list.SharedWith {
-				sharedWith = append(sharedWith, models.SharedUser{
-					ID:    userID.Hex(),
-					Email: "",
-				})
-			}
+		// Build sharedWith array maintaining the original order
+		for _, member := range list.Members {
+			sharedWith = append(sharedWith, models.SharedUser{
+				ID:    member.UserID.Hex(),
+				Email: userMap[member.UserID],
+				Role:  member.Role,
+			})
 		}
 	}
 
@@ -735,6 +713,8 @@ func listToResponse(list *models.List) models.ListResponse {
 		Description: list.Description,
 		Items:       list.Items,
 		SharedWith:  sharedWith,
+		Role:        list.RoleOf(userID),
+		Version:     list.Version,
 		CreatedAt:   list.CreatedAt,
 		UpdatedAt:   list.UpdatedAt,
 	}
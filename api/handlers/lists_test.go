@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestItemsAreAddressedByID(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	list := s.createList(owner, "Groceries")
+
+	s.addItem(owner, list.ID, "Milk")
+	list = s.addItem(owner, list.ID, "Eggs")
+	milk, eggs := list.Items[0], list.Items[1]
+
+	// Deleting the first item must not shift which item the second ID refers to
+	w := s.do(request{method: http.MethodDelete, path: "/lists/" + list.ID + "/items/" + milk.ID.Hex(), token: owner.Token})
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.do(request{method: http.MethodPut, path: "/lists/" + list.ID + "/items/" + eggs.ID.Hex() + "/checked", token: owner.Token, body: map[string]bool{"checked": true}})
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &list)
+	if len(list.Items) != 1 || list.Items[0].ID != eggs.ID || !list.Items[0].Checked {
+		t.Fatalf("items = %+v, want only Eggs, checked", list.Items)
+	}
+
+	w = s.do(request{method: http.MethodDelete, path: "/lists/" + list.ID + "/items/" + milk.ID.Hex(), token: owner.Token})
+	expectError(t, w, http.StatusNotFound, utils.CodeItemNotFound)
+}
+
+func TestIfMatchPreconditions(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	list := s.createList(owner, "Groceries")
+
+	w := s.do(request{method: http.MethodGet, path: "/lists/" + list.ID, token: owner.Token})
+	expectStatus(t, w, http.StatusOK)
+	etag := w.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %q, want %q", etag, `"1"`)
+	}
+
+	addItem := func(ifMatch string) *httptest.ResponseRecorder {
+		return s.do(request{
+			method:  http.MethodPost,
+			path:    "/lists/" + list.ID + "/items",
+			token:   owner.Token,
+			body:    map[string]string{"name": "Milk"},
+			headers: map[string]string{"If-Match": ifMatch},
+		})
+	}
+
+	w = addItem(etag)
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("ETag after write = %q, want %q", got, `"2"`)
+	}
+
+	// The list moved on, so the old tag is stale
+	w = addItem(etag)
+	expectError(t, w, http.StatusPreconditionFailed, utils.CodeVersionConflict)
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("ETag on 412 = %q, want the current %q", got, `"2"`)
+	}
+
+	// If-Match uses strong comparison, so a weak tag never matches
+	expectError(t, addItem(`W/"2"`), http.StatusPreconditionFailed, utils.CodeVersionConflict)
+	expectStatus(t, addItem("*"), http.StatusOK)
+}
+
+func TestListRoles(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	admin := s.signup("admin@example.com")
+	editor := s.signup("editor@example.com")
+	viewer := s.signup("viewer@example.com")
+	outsider := s.signup("outsider@example.com")
+
+	list := s.createList(owner, "Groceries")
+	s.addMember(list.ID, admin, models.RoleAdmin)
+	s.addMember(list.ID, editor, models.RoleEditor)
+	s.addMember(list.ID, viewer, models.RoleViewer)
+
+	tests := []struct {
+		name   string
+		user   testUser
+		method string
+		path   string
+		body   any
+		status int
+		code   utils.ErrorCode
+	}{
+		{"viewer can read", viewer, http.MethodGet, "", nil, http.StatusOK, ""},
+		{"outsider can't read", outsider, http.MethodGet, "", nil, http.StatusForbidden, utils.CodeNotAMember},
+		{"viewer can't add items", viewer, http.MethodPost, "/items", map[string]string{"name": "Milk"}, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"editor can add items", editor, http.MethodPost, "/items", map[string]string{"name": "Milk"}, http.StatusOK, ""},
+		{"editor can't rename", editor, http.MethodPut, "", map[string]string{"name": "Food"}, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"admin can rename", admin, http.MethodPut, "", map[string]string{"name": "Food"}, http.StatusOK, ""},
+		{"admin can't delete", admin, http.MethodDelete, "", nil, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"admin can invite", admin, http.MethodPost, "/invites", map[string]string{"role": "viewer"}, http.StatusCreated, ""},
+		{"admin can't invite admins", admin, http.MethodPost, "/invites", map[string]string{"role": "admin"}, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"editor can't invite", editor, http.MethodPost, "/invites", map[string]string{"role": "viewer"}, http.StatusForbidden, utils.CodeInsufficientRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: tt.method, path: "/lists/" + list.ID + tt.path, token: tt.user.Token, body: tt.body})
+			if tt.code == "" {
+				expectStatus(t, w, tt.status)
+				return
+			}
+			expectError(t, w, tt.status, tt.code)
+		})
+	}
+
+	// Members see their own role
+	w := s.do(request{method: http.MethodGet, path: "/lists/" + list.ID, token: editor.Token})
+	decode(t, w, &list)
+	if list.Role != models.RoleEditor {
+		t.Errorf("editor sees role %q, want %q", list.Role, models.RoleEditor)
+	}
+}
+
+func TestListEventsStream(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	list := s.createList(owner, "Groceries")
+
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/lists/"+list.ID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+owner.Token)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	events := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events <- event
+			}
+		}
+		close(events)
+	}()
+
+	next := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event received")
+			return ""
+		}
+	}
+
+	if event := next(); event != "ready" {
+		t.Fatalf("first event = %q, want ready", event)
+	}
+	s.addItem(owner, list.ID, "Milk")
+	if event := next(); event != "item_added" {
+		t.Fatalf("event after adding an item = %q, want item_added", event)
+	}
+}
+
+// addItemRacingLists is a ListStore that runs a hook before the first AddItem, to stand in
+// for a request that changes the list between the handler's checks and its write
+type addItemRacingLists struct {
+	store.ListStore
+	beforeAddItem func(id primitive.ObjectID)
+}
+
+func (l *addItemRacingLists) AddItem(ctx context.Context, id primitive.ObjectID, version int64, item models.ListItem) (*models.List, error) {
+	if l.beforeAddItem != nil {
+		l.beforeAddItem(id)
+		l.beforeAddItem = nil
+	}
+	return l.ListStore.AddItem(ctx, id, version, item)
+}
+
+func TestWritesRecheckAfterConcurrentChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		demote  bool
+		status  int
+		code    utils.ErrorCode
+		items   int
+	}{
+		// The retry sees that the editor was demoted in the meantime
+		{"role revoked", "", true, http.StatusForbidden, utils.CodeInsufficientRole, 1},
+		{"role revoked with any version", "*", true, http.StatusForbidden, utils.CodeInsufficientRole, 1},
+		// Unrelated changes are retried on top of
+		{"unrelated change", "", false, http.StatusOK, "", 3},
+		// A client that named the version it checked gets to see what changed
+		{"pinned version", "current", false, http.StatusPreconditionFailed, utils.CodeVersionConflict, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			owner := s.signup("owner@example.com")
+			editor := s.signup("editor@example.com")
+			list := s.createList(owner, "Groceries")
+			s.addItem(owner, list.ID, "Milk")
+			s.addMember(list.ID, editor, models.RoleEditor)
+
+			listID, _ := primitive.ObjectIDFromHex(list.ID)
+			lists := s.h.Lists
+			before, err := lists.FindByID(context.Background(), listID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.h.Lists = &addItemRacingLists{ListStore: lists, beforeAddItem: func(id primitive.ObjectID) {
+				if tt.demote {
+					lists.UpdateMemberRole(context.Background(), id, editor.ID, store.AnyVersion, models.RoleViewer)
+					return
+				}
+				lists.AddItem(context.Background(), id, store.AnyVersion, models.ListItem{ID: primitive.NewObjectID(), Name: "Eggs"})
+			}}
+
+			req := request{method: http.MethodPost, path: "/lists/" + list.ID + "/items", token: editor.Token, body: map[string]string{"name": "Bread"}}
+			switch tt.ifMatch {
+			case "":
+			case "current":
+				req.headers = map[string]string{"If-Match": utils.ListETag(before)}
+			default:
+				req.headers = map[string]string{"If-Match": tt.ifMatch}
+			}
+			w := s.do(req)
+			if tt.code != "" {
+				expectError(t, w, tt.status, tt.code)
+			} else {
+				expectStatus(t, w, tt.status)
+			}
+
+			current, err := lists.FindByID(context.Background(), listID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(current.Items) != tt.items {
+				t.Fatalf("list has %d items, want %d", len(current.Items), tt.items)
+			}
+		})
+	}
+}
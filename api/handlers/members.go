@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"bryce-stabenow/grocer-me/events"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HandleUpdateMemberRole handles changing a member's role (admins and the owner).
+// Only the owner can grant admin or change an admin's role.
+func (h *Handler) HandleUpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate member ID
+	memberID, ok := utils.GetAndValidateMemberID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Parse request body
+	var req models.UpdateMemberRoleRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	// Update the member's role
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to update member role", func(list *models.List) bool {
+		// Check the user can manage members
+		if !utils.CheckListRole(w, list, userID, models.RoleAdmin) {
+			return false // Error response already sent
+		}
+
+		// Make sure the target is a member
+		currentRole := list.RoleOf(memberID)
+		if currentRole == models.RoleOwner {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeCannotTargetOwner, "The owner's role cannot be changed")
+			return false
+		}
+		if currentRole == "" {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeMemberNotFound, "Member not found")
+			return false
+		}
+
+		// Only the owner can hand out or take away admin
+		if currentRole == models.RoleAdmin || req.Role == models.RoleAdmin {
+			if !utils.CheckListOwnership(w, list, userID) {
+				return false // Error response already sent
+			}
+		}
+
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.UpdateMemberRole(ctx, listID, memberID, version, req.Role)
+	})
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Only admins can invite, so invites the member minted as an admin stop working
+	if req.Role != models.RoleAdmin && !h.revokeMemberInvites(w, listID, memberID) {
+		return // Error response already sent
+	}
+
+	// Let other members know
+	h.Events.Publish(listID, events.Event{
+		Type:     events.MemberUpdated,
+		Version:  updatedList.Version,
+		ActorID:  userID.Hex(),
+		MemberID: memberID.Hex(),
+		Role:     string(req.Role),
+	})
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
+}
+
+// HandleRemoveMember handles taking a member off a list (admins and the owner).
+// Only the owner can remove an admin.
+func (h *Handler) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate member ID
+	memberID, ok := utils.GetAndValidateMemberID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Remove the member
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to remove member", func(list *models.List) bool {
+		// Check the user can manage members
+		if !utils.CheckListRole(w, list, userID, models.RoleAdmin) {
+			return false // Error response already sent
+		}
+
+		// Make sure the target is a member
+		currentRole := list.RoleOf(memberID)
+		if currentRole == models.RoleOwner {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeCannotTargetOwner, "The owner cannot be removed from the list")
+			return false
+		}
+		if currentRole == "" {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeMemberNotFound, "Member not found")
+			return false
+		}
+
+		// Only the owner can remove an admin
+		if currentRole == models.RoleAdmin && !utils.CheckListOwnership(w, list, userID) {
+			return false // Error response already sent
+		}
+
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.RemoveMember(ctx, listID, memberID, version)
+	})
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Invites the member created can't keep adding people after them
+	if !h.revokeMemberInvites(w, listID, memberID) {
+		return // Error response already sent
+	}
+
+	h.publishMemberRemoved(updatedList, memberID, userID)
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
+}
+
+// HandleLeaveList handles the current user leaving a list they are a member of
+func (h *Handler) HandleLeaveList(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Remove the member
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to leave list", func(list *models.List) bool {
+		// Check the user is on the list
+		if !utils.CheckListAccess(w, list, userID) {
+			return false // Error response already sent
+		}
+
+		// The owner can't leave, or the list would have no owner
+		if list.UserID == userID {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeOwnerCannotLeave, "The owner cannot leave the list. Transfer ownership or delete it instead.")
+			return false
+		}
+
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.RemoveMember(ctx, listID, userID, version)
+	})
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Invites the member created can't keep adding people after them
+	if !h.revokeMemberInvites(w, listID, userID) {
+		return // Error response already sent
+	}
+
+	h.publishMemberRemoved(updatedList, userID, userID)
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
+}
+
+// HandleTransferOwnership handles the owner handing the list to an existing member.
+// The former owner stays on the list as an editor.
+func (h *Handler) HandleTransferOwnership(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Get and validate list ID
+	listID, ok := utils.GetAndValidateListID(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Parse request body
+	var req models.TransferOwnershipRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	newOwnerID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	// Swap owner and member in a single write
+	_, updatedList, ok := h.writeList(w, r, listID, "Failed to transfer ownership", func(list *models.List) bool {
+		// Only the owner can transfer the list
+		if !utils.CheckListOwnership(w, list, userID) {
+			return false // Error response already sent
+		}
+
+		// The new owner must already be a member
+		if newOwnerID == userID {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeAlreadyOwner, "You already own this list")
+			return false
+		}
+		if list.RoleOf(newOwnerID) == "" {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeMemberNotFound, "Member not found")
+			return false
+		}
+
+		// Reject the write if the client is working from a stale version
+		return utils.CheckIfMatch(w, r, list)
+	}, func(ctx context.Context, version int64) (*models.List, error) {
+		return h.Lists.TransferOwnership(ctx, listID, userID, newOwnerID, version, models.RoleEditor)
+	})
+	if !ok {
+		return // Error response already sent
+	}
+
+	// Let other members know
+	h.Events.Publish(listID, events.Event{
+		Type:     events.OwnerChanged,
+		Version:  updatedList.Version,
+		ActorID:  userID.Hex(),
+		MemberID: newOwnerID.Hex(),
+	})
+
+	h.writeListResponse(w, http.StatusOK, updatedList, userID)
+}
+
+// publishMemberRemoved lets the other members know and closes the removed user's event streams
+func (h *Handler) publishMemberRemoved(list *models.List, memberID, actorID primitive.ObjectID) {
+	h.Events.Publish(list.ID, events.Event{
+		Type:     events.MemberRemoved,
+		Version:  list.Version,
+		ActorID:  actorID.Hex(),
+		MemberID: memberID.Hex(),
+	})
+	h.Events.Disconnect(list.ID, memberID)
+}
+
+// revokeMemberInvites revokes the invites the member created for the list. It sends an error
+// response and returns false if that fails.
+func (h *Handler) revokeMemberInvites(w http.ResponseWriter, listID, memberID primitive.ObjectID) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.Invites.RevokeCreatedByOnList(ctx, listID, memberID, time.Now()); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke the member's invites")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+func TestManageMembers(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	admin := s.signup("admin@example.com")
+	otherAdmin := s.signup("other-admin@example.com")
+	editor := s.signup("editor@example.com")
+
+	list := s.createList(owner, "Groceries")
+	s.addMember(list.ID, admin, models.RoleAdmin)
+	s.addMember(list.ID, otherAdmin, models.RoleAdmin)
+	s.addMember(list.ID, editor, models.RoleEditor)
+
+	member := func(user testUser) string { return "/lists/" + list.ID + "/members/" + user.ID.Hex() }
+
+	tests := []struct {
+		name   string
+		user   testUser
+		method string
+		path   string
+		body   any
+		status int
+		code   utils.ErrorCode
+	}{
+		{"editor can't change roles", editor, http.MethodPut, member(admin), map[string]string{"role": "viewer"}, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"admin can't demote an admin", admin, http.MethodPut, member(otherAdmin), map[string]string{"role": "viewer"}, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"admin can't grant admin", admin, http.MethodPut, member(editor), map[string]string{"role": "admin"}, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"admin can't remove an admin", admin, http.MethodDelete, member(otherAdmin), nil, http.StatusForbidden, utils.CodeInsufficientRole},
+		{"nobody can target the owner", admin, http.MethodDelete, member(owner), nil, http.StatusBadRequest, utils.CodeCannotTargetOwner},
+		{"admin can demote an editor", admin, http.MethodPut, member(editor), map[string]string{"role": "viewer"}, http.StatusOK, ""},
+		{"owner can demote an admin", owner, http.MethodPut, member(otherAdmin), map[string]string{"role": "editor"}, http.StatusOK, ""},
+		{"admin can remove a non-admin", admin, http.MethodDelete, member(otherAdmin), nil, http.StatusOK, ""},
+		{"removed members are gone", admin, http.MethodDelete, member(otherAdmin), nil, http.StatusNotFound, utils.CodeMemberNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(request{method: tt.method, path: tt.path, token: tt.user.Token, body: tt.body})
+			if tt.code == "" {
+				expectStatus(t, w, tt.status)
+				return
+			}
+			expectError(t, w, tt.status, tt.code)
+		})
+	}
+
+	w := s.do(request{method: http.MethodGet, path: "/lists/" + list.ID, token: otherAdmin.Token})
+	expectError(t, w, http.StatusForbidden, utils.CodeNotAMember)
+}
+
+func TestDemotingAnAdminRevokesTheirInvites(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	admin := s.signup("admin@example.com")
+	list := s.createList(owner, "Groceries")
+	other := s.createList(owner, "Hardware")
+	s.addMember(list.ID, admin, models.RoleAdmin)
+	s.addMember(other.ID, admin, models.RoleAdmin)
+
+	invite := s.createInvite(admin, list.ID, nil)
+	ownerInvite := s.createInvite(owner, list.ID, nil)
+	otherInvite := s.createInvite(admin, other.ID, nil)
+
+	w := s.do(request{method: http.MethodPut, path: "/lists/" + list.ID + "/members/" + admin.ID.Hex(), token: owner.Token, body: map[string]string{"role": "editor"}})
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.join(s.signup("guest@example.com"), list.ID, invite.Token)
+	expectError(t, w, http.StatusForbidden, utils.CodeInvalidInvite)
+
+	// Other people's invites, and the member's invites for lists they still administer, still work
+	expectStatus(t, s.join(s.signup("friend@example.com"), list.ID, ownerInvite.Token), http.StatusOK)
+	expectStatus(t, s.join(s.signup("neighbour@example.com"), other.ID, otherInvite.Token), http.StatusOK)
+}
+
+func TestRemovedMembersInvitesStopWorking(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	admin := s.signup("admin@example.com")
+	list := s.createList(owner, "Groceries")
+	s.addMember(list.ID, admin, models.RoleAdmin)
+	invite := s.createInvite(admin, list.ID, nil)
+
+	expectStatus(t, s.do(request{method: http.MethodDelete, path: "/lists/" + list.ID + "/members/" + admin.ID.Hex(), token: owner.Token}), http.StatusOK)
+
+	w := s.join(s.signup("guest@example.com"), list.ID, invite.Token)
+	expectError(t, w, http.StatusForbidden, utils.CodeInvalidInvite)
+}
+
+func TestRejoiningNeedsANewInvite(t *testing.T) {
+	tests := []struct {
+		name  string
+		leave func(s *testServer, owner, member testUser, listID string) *httptest.ResponseRecorder
+	}{
+		{"removed", func(s *testServer, owner, member testUser, listID string) *httptest.ResponseRecorder {
+			return s.do(request{method: http.MethodDelete, path: "/lists/" + listID + "/members/" + member.ID.Hex(), token: owner.Token})
+		}},
+		{"left", func(s *testServer, owner, member testUser, listID string) *httptest.ResponseRecorder {
+			return s.do(request{method: http.MethodPost, path: "/lists/" + listID + "/leave", token: member.Token})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			owner := s.signup("owner@example.com")
+			member := s.signup("member@example.com")
+			list := s.createList(owner, "Groceries")
+			s.addMember(list.ID, member, models.RoleEditor)
+			earlier := s.createInvite(owner, list.ID, nil)
+
+			expectStatus(t, tt.leave(s, owner, member, list.ID), http.StatusOK)
+
+			// An invite from before they went can't bring them back, though it still works for others
+			expectError(t, s.join(member, list.ID, earlier.Token), http.StatusForbidden, utils.CodeInvalidInvite)
+			expectStatus(t, s.join(s.signup("guest@example.com"), list.ID, earlier.Token), http.StatusOK)
+
+			// One created since does
+			later := s.createInvite(owner, list.ID, nil)
+			expectStatus(t, s.join(member, list.ID, later.Token), http.StatusOK)
+		})
+	}
+}
+
+func TestLeaveList(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	editor := s.signup("editor@example.com")
+	list := s.createList(owner, "Groceries")
+	s.addMember(list.ID, editor, models.RoleEditor)
+
+	w := s.do(request{method: http.MethodPost, path: "/lists/" + list.ID + "/leave", token: owner.Token})
+	expectError(t, w, http.StatusBadRequest, utils.CodeOwnerCannotLeave)
+
+	expectStatus(t, s.do(request{method: http.MethodPost, path: "/lists/" + list.ID + "/leave", token: editor.Token}), http.StatusOK)
+
+	w = s.do(request{method: http.MethodGet, path: "/lists", token: editor.Token})
+	var lists []models.ListResponse
+	decode(t, w, &lists)
+	if len(lists) != 0 {
+		t.Fatalf("editor still sees %d list(s) after leaving", len(lists))
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner@example.com")
+	editor := s.signup("editor@example.com")
+	outsider := s.signup("outsider@example.com")
+	list := s.createList(owner, "Groceries")
+	s.addMember(list.ID, editor, models.RoleEditor)
+
+	transfer := func(from, to testUser) *httptest.ResponseRecorder {
+		return s.do(request{method: http.MethodPost, path: "/lists/" + list.ID + "/transfer", token: from.Token, body: map[string]string{"user_id": to.ID.Hex()}})
+	}
+
+	expectError(t, transfer(editor, editor), http.StatusForbidden, utils.CodeInsufficientRole)
+	expectError(t, transfer(owner, outsider), http.StatusNotFound, utils.CodeMemberNotFound)
+
+	w := transfer(owner, editor)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &list)
+	if list.UserID != editor.ID.Hex() || list.Role != models.RoleEditor {
+		t.Fatalf("after transfer owner = %s and former owner's role = %q, want %s and editor", list.UserID, list.Role, editor.ID.Hex())
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HandleForgotPassword emails a password reset link if an account exists for the email.
+// It responds the same way either way so it can't be used to discover accounts.
+func (h *Handler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	response := map[string]string{"message": "If an account exists for that email, a password reset link has been sent."}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Don't let anyone flood an inbox. Over the limit, respond as usual but send nothing.
+	// Key on the address exactly as it's looked up, since only that one can be mailed.
+	if ok, _ := h.EmailLimiter.Allow(ctx, req.Email); !ok {
+		utils.JSONResponse(w, http.StatusAccepted, response)
+		return
+	}
+
+	user, err := h.Users.FindByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.JSONResponse(w, http.StatusAccepted, response)
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find user")
+		return
+	}
+
+	rawToken, err := generateOpaqueToken()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate reset token")
+		return
+	}
+
+	now := time.Now()
+	token := models.PasswordResetToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: now.Add(config.Current.Auth.PasswordResetTTL),
+		CreatedAt: now,
+	}
+	if err := h.PasswordResets.Create(ctx, &token); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to create reset token")
+		return
+	}
+
+	// Send in the background so the response time doesn't reveal whether the account exists
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.Current.Server.AppURL, url.QueryEscape(rawToken))
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GrocerMe password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your GrocerMe account.\n\n"+
+			"Use this link to choose a new password. It expires in %d minutes and can only be used once:\n\n%s\n\n"+
+			"If you didn't ask for this, you can ignore this email.\n",
+			int(config.Current.Auth.PasswordResetTTL.Minutes()), link),
+	})
+
+	utils.JSONResponse(w, http.StatusAccepted, response)
+}
+
+// HandleResetPassword sets a new password using a reset token and signs the user out everywhere
+func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.DecodeErrorResponse(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Look up the stored token
+	token, err := h.PasswordResets.FindByHash(ctx, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidResetToken, "This reset link is invalid or has expired")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find reset token")
+		return
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil || now.After(token.ExpiresAt) {
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidResetToken, "This reset link is invalid or has expired")
+		return
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 10)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to hash password")
+		return
+	}
+
+	// Only one request can use a token
+	if err := h.PasswordResets.MarkUsed(ctx, token.ID, now); err != nil {
+		if errors.Is(err, store.ErrTokenUsed) {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidResetToken, "This reset link is invalid or has expired")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to use reset token")
+		return
+	}
+
+	passwordHash := string(hashedPassword)
+	if _, err := h.Users.Update(ctx, token.UserID, store.UserUpdate{PasswordHash: &passwordHash}); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidResetToken, "This reset link is invalid or has expired")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to update password")
+		return
+	}
+
+	// Other outstanding reset links stop working, and so does every existing session
+	if err := h.PasswordResets.InvalidateUser(ctx, token.UserID, now); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to invalidate reset tokens")
+		return
+	}
+	if err := h.revokeAllSessions(ctx, r, token.UserID); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke sessions")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "Password has been reset. Please sign in with your new password."})
+}
+
+// sendMail sends a message in the background, logging failures
+func (h *Handler) sendMail(msg mailer.Message) {
+	h.mail.Add(1)
+	go func() {
+		defer h.mail.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := h.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// WaitForMail waits for messages being sent in the background, giving up when ctx is done
+func (h *Handler) WaitForMail(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.mail.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+
+	// Unknown addresses get the same answer, so accounts can't be discovered
+	for _, email := range []string{user.Email, "nobody@example.com"} {
+		w := s.do(request{method: http.MethodPost, path: "/password/forgot", body: map[string]string{"email": email}})
+		expectStatus(t, w, http.StatusAccepted)
+	}
+	token := linkToken(t, s.nextMail(user.Email, "Reset your GrocerMe password"))
+
+	reset := func(token string) *httptest.ResponseRecorder {
+		return s.do(request{method: http.MethodPost, path: "/password/reset", body: map[string]string{"token": token, "password": "new-password"}})
+	}
+
+	expectError(t, reset("made-up-token"), http.StatusBadRequest, utils.CodeInvalidResetToken)
+	expectStatus(t, reset(token), http.StatusOK)
+	expectError(t, reset(token), http.StatusBadRequest, utils.CodeInvalidResetToken)
+
+	w := s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": user.Email, "password": testPassword}})
+	expectError(t, w, http.StatusUnauthorized, utils.CodeInvalidCredentials)
+	w = s.do(request{method: http.MethodPost, path: "/signin", body: map[string]string{"email": user.Email, "password": "new-password"}})
+	expectStatus(t, w, http.StatusOK)
+
+	// Resetting signs out every refresh token
+	w = s.do(request{method: http.MethodPost, path: "/token/refresh", body: map[string]string{"refresh_token": user.RefreshToken}})
+	expectError(t, w, http.StatusUnauthorized, utils.CodeInvalidRefreshToken)
+}
+
+func TestForgotPasswordLimitFollowsTheLookup(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.RateLimit.EmailLimit = 1
+	})
+	user := s.signup("ann@example.com")
+
+	// A differently cased address matches no account, so it mustn't use up the real one's limit
+	for _, email := range []string{"ANN@example.com", user.Email} {
+		w := s.do(request{method: http.MethodPost, path: "/password/forgot", body: map[string]string{"email": email}})
+		expectStatus(t, w, http.StatusAccepted)
+	}
+	s.nextMail(user.Email, "Reset your GrocerMe password")
+}
+
+// blockingMailer holds every message until release is closed
+type blockingMailer struct {
+	release chan struct{}
+}
+
+// Send waits for release
+func (m blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	return nil
+}
+
+func TestWaitForMail(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+	s.nextMail(user.Email, "Verify your GrocerMe email address") // Sent before the mailer is swapped
+	release := make(chan struct{})
+	s.h.Mailer = blockingMailer{release: release}
+
+	w := s.do(request{method: http.MethodPost, path: "/password/forgot", body: map[string]string{"email": user.Email}})
+	expectStatus(t, w, http.StatusAccepted)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.h.WaitForMail(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForMail with mail still sending = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := s.h.WaitForMail(context.Background()); err != nil {
+		t.Fatalf("WaitForMail once sent = %v, want nil", err)
+	}
+}
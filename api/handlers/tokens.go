@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// accessTokenCookie holds the short-lived JWT
+	accessTokenCookie = "jwt_token"
+	// refreshTokenCookie holds the long-lived opaque refresh token
+	refreshTokenCookie = "refresh_token"
+)
+
+// HandleRefreshToken exchanges a refresh token for a new access token and a new refresh token.
+// Each refresh token can be used once; presenting a used token again revokes its whole family.
+func (h *Handler) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	// Prefer the cookie, fall back to the request body for non-browser clients
+	rawToken := ""
+	if cookie, err := r.Cookie(refreshTokenCookie); err == nil && cookie != nil {
+		rawToken = cookie.Value
+	}
+	if rawToken == "" && r.ContentLength != 0 {
+		var req models.RefreshTokenRequest
+		if err := utils.DecodeJSON(r, &req); err != nil {
+			utils.DecodeErrorResponse(w, err)
+			return
+		}
+		rawToken = req.RefreshToken
+	}
+	if rawToken == "" {
+		utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeAuthRequired, "Refresh token required. Please sign in.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Look up the stored token
+	token, err := h.RefreshTokens.FindByHash(ctx, hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			clearAuthCookies(w)
+			utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeInvalidRefreshToken, "Invalid refresh token")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find refresh token")
+		return
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		clearAuthCookies(w)
+		utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeInvalidRefreshToken, "Refresh token is no longer valid. Please sign in again.")
+		return
+	}
+
+	// Rotate: only one request can consume a token. Losing that race, or presenting a
+	// token that was already rotated, means it was copied, so the whole family goes.
+	if token.UsedAt != nil || h.RefreshTokens.MarkUsed(ctx, token.ID, now) != nil {
+		if err := h.RefreshTokens.RevokeFamily(ctx, token.FamilyID, now); err != nil {
+			utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to revoke refresh tokens")
+			return
+		}
+		clearAuthCookies(w)
+		utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeRefreshTokenReused, "Refresh token reuse detected. Please sign in again.")
+		return
+	}
+
+	tokens, err := h.issueTokens(ctx, w, token.UserID, token.FamilyID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate token")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, tokens)
+}
+
+// issueTokens mints an access token and a refresh token in the given family,
+// stores the refresh token and sets both as HTTP-only cookies
+func (h *Handler) issueTokens(ctx context.Context, w http.ResponseWriter, userID, familyID primitive.ObjectID) (*models.TokenResponse, error) {
+	accessToken, expiresAt, err := generateToken(userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	rawRefreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshToken := models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(rawRefreshToken),
+		ExpiresAt: now.Add(config.Current.Auth.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := h.RefreshTokens.Create(ctx, &refreshToken); err != nil {
+		return nil, err
+	}
+
+	setAuthCookie(w, accessTokenCookie, accessToken, int(config.Current.Auth.AccessTokenTTL.Seconds()))
+	setAuthCookie(w, refreshTokenCookie, rawRefreshToken, int(config.Current.Auth.RefreshTokenTTL.Seconds()))
+
+	return &models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: rawRefreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// clearAuthCookies expires both auth cookies
+func clearAuthCookies(w http.ResponseWriter) {
+	setAuthCookie(w, accessTokenCookie, "", -1)
+	setAuthCookie(w, refreshTokenCookie, "", -1)
+}
+
+// setAuthCookie sets an HttpOnly auth cookie with the configured domain, Secure flag and SameSite mode
+func setAuthCookie(w http.ResponseWriter, name, value string, maxAge int) {
+	cookies := config.Current.Cookies
+	utils.SetCookie(w, name, value, maxAge, "/", cookies.Domain, cookies.Secure, true, cookies.SameSiteMode())
+}
+
+// generateOpaqueToken returns a new random URL-safe token, used for refresh and reset tokens
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateTokenID returns a random ID for the jti claim of an access token
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a token, which is what gets stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/middleware"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// verificationTokenType marks a JWT as an email verification token and selects its signing key
+	verificationTokenType = "verify_email"
+
+	// Actions the email verification policy can block
+	policyInvite = "invite"
+	policyShare  = "share"
+)
+
+// errInvalidVerification is returned when a verification token is malformed, forged or expired
+var errInvalidVerification = errors.New("invalid verification token")
+
+// HandleVerifyEmail marks the user's email as verified using the token from the verification email
+func (h *Handler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	userID, email, err := parseVerificationToken(r.URL.Query().Get("token"))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidVerification, "This verification link is invalid or has expired")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Only verifies the address the link was sent to, in case the email changed since
+	user, err := h.Users.VerifyEmail(ctx, userID, email, time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeInvalidVerification, "This verification link is invalid or has expired")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to verify email")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Email verified successfully",
+		"user":    user,
+	})
+}
+
+// HandleResendVerification sends a new verification email to the current user
+func (h *Handler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated user ID
+	userID, ok := utils.GetAuthenticatedUser(w, r)
+	if !ok {
+		return // Error response already sent
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.Users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			utils.ErrorResponse(w, http.StatusNotFound, utils.CodeUserNotFound, "User not found")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find user")
+		return
+	}
+
+	if user.EmailVerified {
+		utils.ErrorResponse(w, http.StatusBadRequest, utils.CodeEmailAlreadyVerified, "Your email is already verified")
+		return
+	}
+
+	// Don't let anyone flood an inbox
+	if ok, retryAfter := h.EmailLimiter.Allow(ctx, user.Email); !ok {
+		middleware.TooManyRequests(w, retryAfter)
+		return
+	}
+
+	if err := h.sendVerificationEmail(user); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate verification token")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusAccepted, map[string]string{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail emails the user a link that verifies their current address
+func (h *Handler) sendVerificationEmail(user *models.User) error {
+	token, err := generateVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.Current.Server.AppURL, url.QueryEscape(token))
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GrocerMe email address",
+		Body: fmt.Sprintf("Please confirm this is your email address by opening the link below. "+
+			"It expires in %d hours:\n\n%s\n\n"+
+			"If you didn't create a GrocerMe account, you can ignore this email.\n",
+			int(config.Current.Auth.EmailVerificationTTL.Hours()), link),
+	})
+	return nil
+}
+
+// checkEmailVerified enforces the email verification policy for an action.
+// It sends a 403 and returns false if the user has to verify their email first.
+func (h *Handler) checkEmailVerified(w http.ResponseWriter, userID primitive.ObjectID, action string) bool {
+	switch config.Current.Auth.EmailVerificationPolicy {
+	case "none":
+		return true
+	case policyInvite:
+		if action != policyInvite {
+			return true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.Users.FindByID(ctx, userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to find user")
+		return false
+	}
+	if !user.EmailVerified {
+		utils.ErrorResponse(w, http.StatusForbidden, utils.CodeEmailNotVerified, "Please verify your email address first")
+		return false
+	}
+	return true
+}
+
+// generateVerificationToken signs a token that verifies the given address for the user
+func generateVerificationToken(userID primitive.ObjectID, email string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"typ":     verificationTokenType,
+		"user_id": userID.Hex(),
+		"email":   email,
+		"iat":     now.Unix(),
+		"exp":     now.Add(config.Current.Auth.EmailVerificationTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.Current.Auth.SigningKey(verificationTokenType))
+}
+
+// parseVerificationToken verifies a verification token and returns the user and address it is for
+func parseVerificationToken(tokenString string) (primitive.ObjectID, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return config.Current.Auth.SigningKey(verificationTokenType), nil
+	})
+	if err != nil || !token.Valid {
+		return primitive.NilObjectID, "", errInvalidVerification
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != verificationTokenType {
+		return primitive.NilObjectID, "", errInvalidVerification
+	}
+
+	userHex, _ := claims["user_id"].(string)
+	email, _ := claims["email"].(string)
+	userID, err := primitive.ObjectIDFromHex(userHex)
+	if err != nil || email == "" {
+		return primitive.NilObjectID, "", errInvalidVerification
+	}
+	return userID, email, nil
+}
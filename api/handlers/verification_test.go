@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+func TestEmailVerification(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.Auth.EmailVerificationPolicy = policyInvite
+	})
+	user := s.signup("ann@example.com")
+	token := linkToken(t, s.nextMail(user.Email, "Verify"))
+	list := s.createList(user, "Groceries")
+
+	// Unverified accounts can't invite under the invite policy
+	w := s.do(request{method: http.MethodPost, path: "/lists/" + list.ID + "/invites", token: user.Token})
+	expectError(t, w, http.StatusForbidden, utils.CodeEmailNotVerified)
+
+	w = s.do(request{method: http.MethodGet, path: "/verify-email?token=not-a-jwt"})
+	expectError(t, w, http.StatusBadRequest, utils.CodeInvalidVerification)
+
+	w = s.do(request{method: http.MethodGet, path: "/verify-email?token=" + url.QueryEscape(token)})
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.do(request{method: http.MethodGet, path: "/me", token: user.Token})
+	var me models.User
+	decode(t, w, &me)
+	if !me.EmailVerified {
+		t.Fatal("email is not verified after following the link")
+	}
+
+	w = s.do(request{method: http.MethodPost, path: "/lists/" + list.ID + "/invites", token: user.Token})
+	expectStatus(t, w, http.StatusCreated)
+
+	w = s.do(request{method: http.MethodPost, path: "/verify-email/resend", token: user.Token})
+	expectError(t, w, http.StatusBadRequest, utils.CodeEmailAlreadyVerified)
+}
+
+func TestVerificationLinkOnlyVerifiesItsAddress(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("ann@example.com")
+	token := linkToken(t, s.nextMail(user.Email, "Verify"))
+
+	w := s.do(request{method: http.MethodPost, path: "/me/email", token: user.Token, body: map[string]string{"email": "ann@example.org", "password": testPassword}})
+	expectStatus(t, w, http.StatusOK)
+
+	// The link was for the old address
+	w = s.do(request{method: http.MethodGet, path: "/verify-email?token=" + url.QueryEscape(token)})
+	expectError(t, w, http.StatusBadRequest, utils.CodeInvalidVerification)
+
+	token = linkToken(t, s.nextMail("ann@example.org", "Verify"))
+	expectStatus(t, s.do(request{method: http.MethodGet, path: "/verify-email?token=" + url.QueryEscape(token)}), http.StatusOK)
+}
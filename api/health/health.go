@@ -0,0 +1,127 @@
+// Package health serves the liveness and readiness probes
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bryce-stabenow/grocer-me/utils"
+)
+
+// Check reports whether a dependency is usable, returning an error if it isn't
+type Check func(ctx context.Context) error
+
+// Status values reported by the probes
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Checker runs the readiness checks for the server's dependencies
+type Checker struct {
+	Timeout  time.Duration // How long each check may take
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// DependencyStatus is the result of one dependency's check
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Response is the body of a probe response
+type Response struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks,omitempty"`
+}
+
+// NewChecker creates a Checker whose checks each time out after timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{Timeout: timeout, checks: map[string]Check{}}
+}
+
+// Add registers a dependency check under the given name
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// SetDraining makes readiness fail from now on, so load balancers stop sending new requests
+// while in-flight ones finish
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// HandleLivez reports that the process is up and serving. It checks no dependencies, so an
+// outage elsewhere doesn't get the server restarted.
+func (c *Checker) HandleLivez(w http.ResponseWriter, r *http.Request) {
+	utils.JSONResponse(w, http.StatusOK, Response{Status: StatusOK})
+}
+
+// HandleReadyz runs every dependency check concurrently and reports each one. It returns
+// 503 if any check fails or the server is draining.
+func (c *Checker) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	// Probes must never see a cached answer
+	w.Header().Set("Cache-Control", "no-store")
+
+	if c.draining.Load() {
+		utils.JSONResponse(w, http.StatusServiceUnavailable, Response{Status: StatusDraining})
+		return
+	}
+
+	results := make(map[string]DependencyStatus, len(c.names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		check := c.checks[name]
+		wg.Go(func() {
+			result := c.run(r.Context(), name, check)
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	response := Response{Status: StatusOK, Checks: results}
+	statusCode := http.StatusOK
+	for _, result := range results {
+		if result.Status != StatusOK {
+			response.Status = StatusUnavailable
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
+	utils.JSONResponse(w, statusCode, response)
+}
+
+// run times a single check, giving up after the Checker's timeout. The probe is public, so
+// failure details are logged rather than returned.
+func (c *Checker) run(ctx context.Context, name string, check Check) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := DependencyStatus{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		slog.Warn("Readiness check failed", "dependency", name, "error", err)
+		result.Status = StatusUnavailable
+		result.Error = "check failed"
+		if ctx.Err() != nil {
+			result.Error = "timed out"
+		}
+	}
+	return result
+}
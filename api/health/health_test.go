@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name     string
+		checks   map[string]Check
+		draining bool
+		status   int
+		body     string
+	}{
+		{"all ok", map[string]Check{"mongo": ok}, false, http.StatusOK, StatusOK},
+		{"failing dependency", map[string]Check{"mongo": failing, "other": ok}, false, http.StatusServiceUnavailable, StatusUnavailable},
+		{"timed out dependency", map[string]Check{"mongo": slow}, false, http.StatusServiceUnavailable, StatusUnavailable},
+		{"draining", map[string]Check{"mongo": ok}, true, http.StatusServiceUnavailable, StatusDraining},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(50 * time.Millisecond)
+			for name, check := range tt.checks {
+				checker.Add(name, check)
+			}
+			if tt.draining {
+				checker.SetDraining()
+			}
+
+			w := httptest.NewRecorder()
+			checker.HandleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			var resp Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.body {
+				t.Fatalf("body status = %q, want %q", resp.Status, tt.body)
+			}
+			for name, check := range resp.Checks {
+				if check.Error == "connection refused" {
+					t.Fatalf("check %s leaks its error to the response", name)
+				}
+			}
+		})
+	}
+}
+
+func TestLivenessIgnoresDependencies(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("mongo", func(ctx context.Context) error { return errors.New("down") })
+	checker.SetDraining()
+
+	w := httptest.NewRecorder()
+	checker.HandleLivez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// unsafeFileChars matches characters that shouldn't end up in a file name
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+// LogMailer logs each message instead of sending it, for development and tests.
+// If Dir is set, every message is also written there as an .eml file.
+type LogMailer struct {
+	Dir  string
+	From string
+
+	mu sync.Mutex
+}
+
+// NewLogMailer creates a Mailer that logs messages and writes them to dir, if set
+func NewLogMailer(dir, from string) *LogMailer {
+	return &LogMailer{Dir: dir, From: from}
+}
+
+// Send logs the message and writes it to Dir
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !validHeader(msg.To) || !validHeader(msg.Subject) {
+		return errors.New("mailer: invalid header value")
+	}
+
+	if m.Dir == "" {
+		log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, format(m.From, msg), 0o600); err != nil {
+		return err
+	}
+
+	log.Printf("mail to %s: %s (written to %s)", msg.To, msg.Subject, path)
+	return nil
+}
@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts one connection and records the envelope sender and the message
+func fakeSMTP(t *testing.T) (host string, port int, received <-chan [2]string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan [2]string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+
+		var sender string
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				sender = strings.TrimSpace(line)[len("MAIL FROM:"):]
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 Go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				ch <- [2]string{sender, data.String()}
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestSMTPMailerSplitsFromAddress(t *testing.T) {
+	host, port, received := fakeSMTP(t)
+
+	m, err := NewSMTPMailer(host, port, "", "", "GrocerMe <no-reply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), Message{To: "ann@example.com", Subject: "Hello", Body: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-received
+	if got[0] != "<no-reply@example.com>" {
+		t.Fatalf("envelope sender = %q, want <no-reply@example.com>", got[0])
+	}
+	if !strings.Contains(got[1], "From: \"GrocerMe\" <no-reply@example.com>\r\n") {
+		t.Fatalf("message is missing the From header:\n%s", got[1])
+	}
+}
+
+func TestNewSMTPMailerRejectsBadFrom(t *testing.T) {
+	if _, err := NewSMTPMailer("localhost", 25, "", "", "GrocerMe"); err == nil {
+		t.Fatal("NewSMTPMailer accepted a from address without an address")
+	}
+}
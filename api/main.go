@@ -2,14 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"bryce-stabenow/grocer-me/config"
 	"bryce-stabenow/grocer-me/handlers"
+	"bryce-stabenow/grocer-me/health"
+	"bryce-stabenow/grocer-me/mailer"
+	"bryce-stabenow/grocer-me/metrics"
 	"bryce-stabenow/grocer-me/middleware"
+	"bryce-stabenow/grocer-me/store"
 	"bryce-stabenow/grocer-me/utils"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -18,15 +28,27 @@ import (
 )
 
 func main() {
-	// Initialize config (loads JWT_SECRET)
-	config.Init()
-
-	// Get MongoDB URI from environment variable
-	mongoURI := config.GetMongoURI()
+	// Log JSON lines to stdout. Setting the default also routes the log package through slog.
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
+	// Load config from defaults, the config file, the environment and flags
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	config.Current = cfg
 
 	// Use the SetServerAPIOptions() method to set the version of the Stable API on the client
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(mongoURI).SetServerAPIOptions(serverAPI)
+	opts := options.Client().ApplyURI(cfg.Mongo.URI).SetServerAPIOptions(serverAPI)
+
+	// Time every command for the metrics endpoint
+	opts.SetMonitor(metrics.MongoMonitor())
 
 	// Create a new client and connect to the server
 	client, err := mongo.Connect(opts)
@@ -34,17 +56,11 @@ func main() {
 		log.Fatal("Failed to connect to MongoDB:", err)
 	}
 
-	defer func() {
-		if err = client.Disconnect(context.TODO()); err != nil {
-			log.Fatal("Failed to disconnect from MongoDB:", err)
-		}
-	}()
-
 	// Send a ping to confirm a successful connection
 	if err := client.Ping(context.TODO(), readpref.Primary()); err != nil {
 		log.Fatal("Failed to ping MongoDB:", err)
 	}
-	fmt.Println("Successfully connected to MongoDB!")
+	slog.Info("Successfully connected to MongoDB")
 
 	// Set MongoDB client in config
 	config.SetMongoClient(client)
@@ -52,47 +68,105 @@ func main() {
 	// Initialize router
 	router := utils.NewRouter()
 
+	// Tag every request with an ID first, so even CORS and 404 responses carry it
+	router.Use(middleware.RequestID)
+
+	// Log every request once it has been handled
+	router.Use(middleware.AccessLog(logger))
+
+	// Count and time every request by route
+	router.Use(middleware.Metrics)
+
 	// Apply CORS middleware to all routes
-	router.Use(middleware.CORS)
-
-	// Health check endpoint
-	router.GET("/health", func(w http.ResponseWriter, r *http.Request) {
-		utils.JSONResponse(w, http.StatusOK, map[string]string{"status": "ok"})
-	})
-
-	// Public routes - API endpoints
-	router.POST("/signup", handlers.HandleSignup)
-	router.POST("/signin", handlers.HandleSignin)
-	router.POST("/lists/share/:id", handlers.HandleShareList)
-
-	// Protected routes (require JWT)
-	router.GET("/me", withAuth(handlers.HandleGetMe))
-	router.POST("/logout", withAuth(handlers.HandleLogout))
-
-	// List routes
-	router.POST("/lists", withAuth(handlers.HandleCreateList))
-	router.GET("/lists", withAuth(handlers.HandleGetLists))
-	router.GET("/lists/:id", withAuth(handlers.HandleGetList))
-	router.PUT("/lists/:id", withAuth(handlers.HandleUpdateList))
-	router.DELETE("/lists/:id", withAuth(handlers.HandleDeleteList))
-	router.POST("/lists/:id/items", withAuth(handlers.HandleAddListItem))
-	router.PUT("/lists/:id/items", withAuth(handlers.HandleUpdateListItem))
-	router.DELETE("/lists/:id/items", withAuth(handlers.HandleDeleteListItem))
-	router.PUT("/lists/:id/items/checked", withAuth(handlers.HandleUpdateListItemChecked))
-
-	// Get port from environment or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	router.Use(middleware.CORS(cfg.CORS, cfg.Server.AppURL))
+
+	// Liveness and readiness probes. /health is kept for existing monitors.
+	checker := health.NewChecker(cfg.Server.ReadinessTimeout)
+	checker.Add("mongo", health.PingMongo(config.MongoClient))
+	router.GET("/livez", checker.HandleLivez)
+	router.GET("/readyz", checker.HandleReadyz)
+	router.GET("/health", checker.HandleLivez)
+
+	// Prometheus metrics endpoint
+	router.GET("/metrics", middleware.MetricsAuth(cfg.Metrics.Token)(metrics.Handler()))
+
+	// API routes backed by MongoDB
+	mail, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatal("Failed to set up mailer:", err)
+	}
+	h := handlers.New(store.NewMongoStores(config.DB), mail)
+	h.Register(router)
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	// Event streams never finish on their own, so end them when shutdown starts
+	server.RegisterOnShutdown(h.Events.Close)
+
+	// Serve until the server fails or we're told to stop
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server starting", "port", cfg.Server.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		slog.Error("Server failed", "error", err)
+		exitCode = 1
+	case <-signals.Done():
+		// A second signal kills the process straight away
+		stop()
+		slog.Info("Shutting down, draining connections", "timeout", cfg.Server.ShutdownTimeout)
+
+		// Fail readiness first, so load balancers stop sending traffic before the listener closes
+		checker.SetDraining()
+		time.Sleep(cfg.Server.DrainDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error("Connections did not drain in time, closing them", "error", err)
+			server.Close()
+			exitCode = 1
+		}
+
+		// Requests may have left mail sending in the background, so let it finish in what's left
+		if err := h.WaitForMail(ctx); err != nil {
+			slog.Error("Mail was still sending at shutdown, dropping it", "error", err)
+			exitCode = 1
+		}
+		cancel()
 	}
 
-	fmt.Printf("Server starting on port %s\n", port)
-	if err := http.ListenAndServe(":"+port, router); err != nil {
-		log.Fatal("Failed to start server:", err)
+	// Disconnect from MongoDB once no request can still be using it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = client.Disconnect(ctx)
+	cancel()
+	if err != nil {
+		slog.Error("Failed to disconnect from MongoDB", "error", err)
+		exitCode = 1
 	}
+
+	slog.Info("Server stopped")
+	os.Exit(exitCode)
 }
 
-// withAuth wraps a handler with JWT authentication middleware
-func withAuth(handler http.HandlerFunc) http.HandlerFunc {
-	return middleware.JWTAuth(handler)
+// newMailer sends through SMTP when it is configured and logs mail otherwise
+func newMailer(cfg config.MailConfig) (mailer.Mailer, error) {
+	if cfg.SMTPHost == "" {
+		return mailer.NewLogMailer(cfg.Dir, cfg.From), nil
+	}
+	return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
 }
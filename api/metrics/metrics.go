@@ -0,0 +1,39 @@
+// Package metrics collects the API's metrics and serves them in the Prometheus text format
+package metrics
+
+import (
+	"net/http"
+)
+
+// Default is the registry the API's metrics are registered in and /metrics serves
+var Default = NewRegistry()
+
+var (
+	// latencyBuckets are the histogram bounds for HTTP request latency, in seconds
+	latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// mongoBuckets are the histogram bounds for MongoDB command latency, in seconds
+	mongoBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+)
+
+var (
+	// HTTPRequests counts handled requests by method, route pattern and status code
+	HTTPRequests = Default.NewCounter("http_requests_total",
+		"HTTP requests handled, by route pattern.", "method", "route", "status")
+	// HTTPRequestDuration observes how long requests took to handle, by method and route pattern
+	HTTPRequestDuration = Default.NewHistogram("http_request_duration_seconds",
+		"Time taken to handle HTTP requests, by route pattern.", latencyBuckets, "method", "route")
+	// HTTPRequestsInFlight is the number of requests being handled, including open event streams
+	HTTPRequestsInFlight = Default.NewGauge("http_requests_in_flight",
+		"HTTP requests currently being handled, including open event streams.")
+	// AuthFailures counts requests to protected routes rejected for their access token, by reason
+	AuthFailures = Default.NewCounter("auth_failures_total",
+		"Requests to protected routes rejected by JWT authentication, by reason.", "reason")
+	// MongoCommandDuration observes how long MongoDB commands took, by command, collection and outcome
+	MongoCommandDuration = Default.NewHistogram("mongo_command_duration_seconds",
+		"Time taken by MongoDB commands, by command, collection and outcome.", mongoBuckets, "command", "collection", "outcome")
+)
+
+// Handler serves the default registry's metrics
+func Handler() http.HandlerFunc {
+	return Default.Handler()
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// MongoMonitor returns a command monitor that times every MongoDB command into
+// MongoCommandDuration. Pass it to the client options with SetMonitor.
+func MongoMonitor() *event.CommandMonitor {
+	// Finished events don't name the collection, so remember it from the started event
+	var collections sync.Map
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			collection := ""
+			if first, err := e.Command.IndexErr(0); err == nil {
+				collection, _ = first.Value().StringValueOK()
+			}
+			collections.Store(e.RequestID, collection)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observeCommand(&collections, e.CommandFinishedEvent, "success")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			observeCommand(&collections, e.CommandFinishedEvent, "failure")
+		},
+	}
+}
+
+// observeCommand records a finished command's duration
+func observeCommand(collections *sync.Map, e event.CommandFinishedEvent, outcome string) {
+	collection := ""
+	if value, ok := collections.LoadAndDelete(e.RequestID); ok {
+		collection = value.(string)
+	}
+	MongoCommandDuration.Observe(e.Duration.Seconds(), e.CommandName, collection, outcome)
+}
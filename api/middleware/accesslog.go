@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+// AccessLog writes a structured log line for every request once it has been handled.
+// It belongs after RequestID, so the line carries the request ID.
+func AccessLog(logger *slog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			info := &utils.RequestInfo{}
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next(recorder, utils.SetRequestInfo(r, info))
+
+			level := slog.LevelInfo
+			if recorder.Status() >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			attrs := []slog.Attr{
+				slog.String("request_id", utils.GetRequestID(r)),
+				slog.String("method", r.Method),
+				slog.String("route", utils.GetRoutePattern(r)),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", recorder.Status()),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("ip", utils.ClientIP(r, config.Current.Server.TrustProxyHeaders)),
+			}
+			if info.UserID != "" {
+				attrs = append(attrs, slog.String("user_id", info.UserID))
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		}
+	}
+}
+
+// statusRecorder remembers the status code and body size a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status code before sending it
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes written, which implies a 200 if no status was sent yet
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, which event streams rely on
+func (sr *statusRecorder) Flush() {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	http.NewResponseController(sr.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Status returns the status code sent, which is 200 if the handler wrote nothing
+func (sr *statusRecorder) Status() int {
+	if sr.status == 0 {
+		return http.StatusOK
+	}
+	return sr.status
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// BodyLimit caps request bodies at maxBytes. Reading past the cap fails, and DecodeJSON
+// reports it as a 413.
+func BodyLimit(maxBytes int64) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next(w, r)
+		}
+	}
+}
@@ -2,26 +2,56 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/utils"
 )
 
-// CORS middleware handles Cross-Origin Resource Sharing
-func CORS(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		w.Header().Set("Access-Control-Max-Age", "3600")
-
-		// Handle preflight OPTIONS request
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
+// CORS handles Cross-Origin Resource Sharing for the configured origins. With no origins
+// configured only the web app's own origin is allowed, and "*" allows any origin.
+func CORS(cfg config.CORSConfig, appURL string) func(http.HandlerFunc) http.HandlerFunc {
+	origins := cfg.AllowedOrigins
+	if len(origins) == 0 {
+		origins = []string{config.Origin(appURL)}
+	}
+	anyOrigin := slices.Contains(origins, "*")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
 
-		next(w, r)
+			// The response depends on the origin, so caches must keep them apart
+			w.Header().Add("Vary", "Origin")
+
+			// Set CORS headers for allowed origins only. Credentials rule out a literal "*",
+			// so the origin is echoed back.
+			allowed := origin != "" && (anyOrigin || slices.Contains(origins, origin))
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, If-Match, X-Request-ID")
+				w.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After, X-Request-ID")
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+
+			// Handle preflight OPTIONS request, advertising the methods the router has for the path.
+			// A path with no routes falls through to the router's 404.
+			if r.Method == http.MethodOptions {
+				if methods := utils.GetAllowedMethods(r); len(methods) > 0 {
+					if allowed {
+						w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+					}
+					w.Header().Set("Allow", strings.Join(methods, ", "))
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+
+			next(w, r)
+		}
 	}
 }
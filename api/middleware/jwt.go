@@ -1,127 +1,176 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
 	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/metrics"
+	"bryce-stabenow/grocer-me/store"
 	"bryce-stabenow/grocer-me/utils"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// JWTAuth validates JWT tokens and extracts user ID
-func JWTAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var tokenString string
-
-		// First, try to get token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			// Extract token from "Bearer <token>"
-			parts := strings.Split(authHeader, " ")
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				tokenString = parts[1]
-			}
-		}
+// AccessTokenType is the typ claim of access tokens. Other JWTs the API issues carry their own type.
+const AccessTokenType = "access"
+
+var (
+	// ErrNoToken is returned when a request carries no access token
+	ErrNoToken = errors.New("no token")
+	// ErrTokenRevoked is returned when an access token was revoked before it expired
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrRevocationCheck is returned when the revocation store can't be reached. The token
+	// may well be valid, so callers shouldn't treat this as signed out.
+	ErrRevocationCheck = errors.New("revocation check failed")
+)
 
-		// If not in header, try to get from cookie
-		if tokenString == "" {
-			cookie, err := r.Cookie("jwt_token")
-			if err == nil && cookie != nil {
-				tokenString = cookie.Value
-			}
-		}
+// Authenticator validates JWT access tokens and checks them against a revocation store
+type Authenticator struct {
+	Revocations store.RevocationStore
+}
 
-		// If still no token, return unauthorized
-		if tokenString == "" {
-			utils.ErrorResponse(w, http.StatusUnauthorized, "Authorization required. Please sign in.")
-			return
-		}
+// NewAuthenticator creates an Authenticator backed by the given revocation store
+func NewAuthenticator(revocations store.RevocationStore) *Authenticator {
+	return &Authenticator{Revocations: revocations}
+}
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+// JWTAuth validates JWT tokens and extracts user ID
+func (a *Authenticator) JWTAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := a.Authenticate(r)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNoToken):
+				metrics.AuthFailures.Inc("no_token")
+				utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeAuthRequired, "Authorization required. Please sign in.")
+			case errors.Is(err, ErrRevocationCheck):
+				utils.ErrorResponse(w, http.StatusInternalServerError, utils.CodeInternal, "Failed to check session")
+			case errors.Is(err, ErrTokenRevoked):
+				metrics.AuthFailures.Inc("revoked")
+				utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeSessionRevoked, "Session has been signed out. Please sign in again.")
+			default:
+				metrics.AuthFailures.Inc("invalid")
+				utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeInvalidToken, "Invalid or expired token")
 			}
-			return []byte(config.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid or expired token")
-			return
-		}
-
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid token claims")
-			return
-		}
-
-		// Extract user ID from claims
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid user ID in token")
 			return
 		}
 
-		// Store user ID in context
-		r = utils.SetUserID(r, userID)
+		// Store user ID and token details in context
+		r = utils.SetUserID(r, token.UserID)
+		r = utils.SetToken(r, token)
 		next(w, r)
 	}
 }
 
 // ExtractUserID extracts user ID from JWT token (used for public endpoints that optionally require auth)
-func ExtractUserID(r *http.Request) (string, error) {
-	var tokenString string
-
-	// First, try to get token from Authorization header
-	authHeader := r.Header.Get("Authorization")
-	if authHeader != "" {
-		parts := strings.Split(authHeader, " ")
-		if len(parts) == 2 && parts[0] == "Bearer" {
-			tokenString = parts[1]
-		}
-	}
-
-	// If not in header, try to get from cookie
-	if tokenString == "" {
-		cookie, err := r.Cookie("jwt_token")
-		if err == nil && cookie != nil {
-			tokenString = cookie.Value
-		}
+func (a *Authenticator) ExtractUserID(r *http.Request) (string, error) {
+	token, err := a.Authenticate(r)
+	if err != nil {
+		return "", err
 	}
+	return token.UserID, nil
+}
 
-	// If no token found, return error
+// Authenticate parses and validates the request's access token, including revocation
+func (a *Authenticator) Authenticate(r *http.Request) (utils.TokenInfo, error) {
+	tokenString := tokenFromRequest(r)
 	if tokenString == "" {
-		return "", jwt.ErrSignatureInvalid
+		return utils.TokenInfo{}, ErrNoToken
 	}
 
 	// Parse and validate token
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte(config.JWTSecret), nil
+		return config.Current.Auth.SigningKey(AccessTokenType), nil
 	})
-
-	if err != nil || !token.Valid {
-		return "", err
+	if err != nil {
+		return utils.TokenInfo{}, err
+	}
+	if !token.Valid {
+		return utils.TokenInfo{}, jwt.ErrSignatureInvalid
 	}
 
 	// Extract claims
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", jwt.ErrSignatureInvalid
+	if !ok || claims["typ"] != AccessTokenType {
+		return utils.TokenInfo{}, jwt.ErrTokenInvalidClaims
 	}
 
 	// Extract user ID from claims
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		return "", jwt.ErrSignatureInvalid
+		return utils.TokenInfo{}, jwt.ErrTokenInvalidClaims
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return utils.TokenInfo{}, jwt.ErrTokenInvalidClaims
+	}
+
+	info := utils.TokenInfo{UserID: userID}
+	info.ID, _ = claims["jti"].(string)
+	info.IssuedAt = issuedAt(claims)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Time
+	}
+
+	// Check whether the token was signed out before it expired
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	revoked, err := a.Revocations.IsRevoked(ctx, info.ID, userObjectID, info.IssuedAt)
+	if err != nil {
+		return utils.TokenInfo{}, fmt.Errorf("%w: %w", ErrRevocationCheck, err)
+	}
+	if revoked {
+		return utils.TokenInfo{}, ErrTokenRevoked
+	}
+
+	return info, nil
+}
+
+// tokenFromRequest reads the access token from the Authorization header or the cookie
+func tokenFromRequest(r *http.Request) string {
+	// First, try to get token from Authorization header
+	authHeader := r.Header.Get("Authorization")
+	if authHeader != "" {
+		// Extract token from "Bearer <token>"
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1]
+		}
+	}
+
+	// If not in header, try to get from cookie
+	cookie, err := r.Cookie("jwt_token")
+	if err == nil && cookie != nil {
+		return cookie.Value
 	}
 
-	return userID, nil
+	return ""
+}
+
+// IssuedAtClaim returns the iat claim for a token issued at t. It keeps milliseconds, so a
+// token issued just after a user-wide revocation isn't caught by it.
+func IssuedAtClaim(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+// issuedAt reads the iat claim to the millisecond, since jwt rounds dates to whole seconds.
+// Tokens without one come back as the zero time.
+func issuedAt(claims jwt.MapClaims) time.Time {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(math.Round(iat * 1000)))
 }
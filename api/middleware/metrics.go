@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bryce-stabenow/grocer-me/metrics"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+// unmatchedRoute labels requests that matched no route, so stray paths can't grow the
+// number of series without bound
+const unmatchedRoute = "unmatched"
+
+// Metrics records request counts, latency and requests in flight, labelled by route pattern
+func Metrics(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		next(recorder, r)
+
+		route := utils.GetRoutePattern(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.HTTPRequests.Inc(r.Method, route, strconv.Itoa(recorder.Status()))
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	}
+}
+
+// MetricsAuth requires the given bearer token to scrape metrics. An empty token leaves the
+// endpoint open, for deployments that keep it off the public network.
+func MetricsAuth(token string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				sent, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+				if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+					utils.ErrorResponse(w, http.StatusUnauthorized, utils.CodeAuthRequired, "A valid metrics token is required")
+					return
+				}
+			}
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bryce-stabenow/grocer-me/config"
+	"bryce-stabenow/grocer-me/store"
+	"bryce-stabenow/grocer-me/utils"
+)
+
+// RateLimiter allows a fixed number of requests per key in each window.
+// If the store fails, requests are let through rather than locking everyone out.
+type RateLimiter struct {
+	Store  store.RateLimitStore
+	Name   string // Keeps the counters of different limiters apart
+	Limit  int
+	Window time.Duration
+}
+
+// NewRateLimiter creates a RateLimiter that keeps its counters in the given store
+func NewRateLimiter(counters store.RateLimitStore, name string, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{Store: counters, Name: name, Limit: limit, Window: window}
+}
+
+// Allow counts a request for the key and reports whether it is within the limit.
+// If it isn't, it also returns how long until the key may try again.
+func (l *RateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration) {
+	now := time.Now()
+	count, resetAt, err := l.Store.Increment(ctx, l.Name+":"+key, l.Window, now)
+	if err != nil {
+		log.Printf("Rate limiter %s failed, allowing request: %v", l.Name, err)
+		return true, 0
+	}
+	if count > l.Limit {
+		return false, resetAt.Sub(now)
+	}
+	return true, 0
+}
+
+// ByIP limits requests per client IP. It can wrap single routes or be passed to Router.Use.
+func (l *RateLimiter) ByIP(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if ok, retryAfter := l.Allow(ctx, utils.ClientIP(r, config.Current.Server.TrustProxyHeaders)); !ok {
+			TooManyRequests(w, retryAfter)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Lockout locks an account for progressively longer after repeated failed password attempts.
+// Accounts are keyed by email, whether or not they exist, so lockouts don't reveal which do.
+type Lockout struct {
+	Store     store.RateLimitStore
+	Threshold int           // Failures before the first lock
+	Window    time.Duration // How long failures are remembered
+	BaseDelay time.Duration // Length of the first lock
+	MaxDelay  time.Duration // Longest a single lock can be
+}
+
+// NewLockout creates a Lockout using the limits from config
+func NewLockout(counters store.RateLimitStore) *Lockout {
+	return &Lockout{
+		Store:     counters,
+		Threshold: config.Current.RateLimit.LockoutThreshold,
+		Window:    config.Current.RateLimit.LockoutWindow,
+		BaseDelay: config.Current.RateLimit.LockoutBaseDelay,
+		MaxDelay:  config.Current.RateLimit.LockoutMaxDelay,
+	}
+}
+
+// Check returns how long the account is still locked for, or zero if it isn't
+func (l *Lockout) Check(ctx context.Context, email string) time.Duration {
+	now := time.Now()
+	count, lockedUntil, err := l.Store.Peek(ctx, l.lockKey(email), now)
+	if err != nil {
+		log.Printf("Lockout check failed, allowing attempt: %v", err)
+		return 0
+	}
+	if count == 0 {
+		return 0
+	}
+	return lockedUntil.Sub(now)
+}
+
+// Fail records a failed attempt and locks the account once there have been too many
+func (l *Lockout) Fail(ctx context.Context, email string) {
+	now := time.Now()
+	failures, _, err := l.Store.Increment(ctx, l.failureKey(email), l.Window, now)
+	if err != nil {
+		log.Printf("Failed to record failed attempt: %v", err)
+		return
+	}
+	if failures < l.Threshold {
+		return
+	}
+
+	// Attempts are refused while locked, so each new failure here comes after the last lock ended
+	if _, _, err := l.Store.Increment(ctx, l.lockKey(email), l.delay(failures), now); err != nil {
+		log.Printf("Failed to lock account: %v", err)
+	}
+}
+
+// Succeed forgets the account's failed attempts
+func (l *Lockout) Succeed(ctx context.Context, email string) {
+	if err := l.Store.Reset(ctx, l.failureKey(email)); err != nil {
+		log.Printf("Failed to reset failed attempts: %v", err)
+	}
+}
+
+// delay doubles the lock for every failure past the threshold
+func (l *Lockout) delay(failures int) time.Duration {
+	doublings := failures - l.Threshold
+	if doublings > 30 {
+		return l.MaxDelay
+	}
+	delay := l.BaseDelay << doublings
+	if delay <= 0 || delay > l.MaxDelay {
+		return l.MaxDelay
+	}
+	return delay
+}
+
+func (l *Lockout) failureKey(email string) string {
+	return "lockout-failures:" + normalizeEmail(email)
+}
+
+func (l *Lockout) lockKey(email string) string {
+	return "lockout:" + normalizeEmail(email)
+}
+
+// normalizeEmail stops case and whitespace variations from getting a fresh set of attempts
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// TooManyRequests sends a 429 telling the client how many seconds to wait
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	utils.ErrorResponse(w, http.StatusTooManyRequests, utils.CodeRateLimited, "Too many attempts. Please try again later.")
+}
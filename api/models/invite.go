@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite represents an invitation to join a list. The invite token handed out to
+// people is signed and references the invite by ID, so it can be revoked or used up.
+type Invite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ListID    primitive.ObjectID `json:"list_id" bson:"list_id"`
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	Role      Role               `json:"role" bson:"role"` // Role given to people who join
+	MaxUses   int                `json:"max_uses" bson:"max_uses"`
+	Uses      int                `json:"uses" bson:"uses"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// CreateInviteRequest represents the request body for creating an invite
+type CreateInviteRequest struct {
+	Role           Role `json:"role,omitempty" binding:"omitempty,oneof=viewer editor admin"`
+	ExpiresInHours *int `json:"expires_in_hours,omitempty" binding:"omitempty,min=1,max=720"`
+	MaxUses        *int `json:"max_uses,omitempty" binding:"omitempty,min=1,max=100"`
+}
+
+// JoinListRequest represents the request body for joining a list with an invite
+type JoinListRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// InviteResponse represents the response for invite operations.
+// Token is only included when the invite is created.
+type InviteResponse struct {
+	ID        string    `json:"id"`
+	ListID    string    `json:"list_id"`
+	CreatedBy string    `json:"created_by"`
+	Role      Role      `json:"role"`
+	Token     string    `json:"token,omitempty"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
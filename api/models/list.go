@@ -8,18 +8,74 @@ import (
 
 // List represents a list document in MongoDB
 type List struct {
-	ID          primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
-	UserID      primitive.ObjectID   `json:"user_id" bson:"user_id"`
-	Name        string               `json:"name" bson:"name"`
-	Description string               `json:"description,omitempty" bson:"description,omitempty"`
-	Items       []ListItem           `json:"items" bson:"items"`
-	SharedWith  []primitive.ObjectID `json:"shared_with" bson:"shared_with"`
-	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time            `json:"updated_at" bson:"updated_at"`
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Items       []ListItem         `json:"items" bson:"items"`
+	Members     []Membership       `json:"members" bson:"members"`
+	// Removed holds when each former member, keyed by user ID hex, left or was removed.
+	// Invites created before then can't bring them back.
+	Removed   map[string]time.Time `json:"-" bson:"removed,omitempty"`
+	Version   int64                `json:"version" bson:"version"`
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// Role is a user's permission level on a list
+type Role string
+
+const (
+	// RoleViewer can read the list
+	RoleViewer Role = "viewer"
+	// RoleEditor can also add, change and remove items
+	RoleEditor Role = "editor"
+	// RoleAdmin can also edit the list itself and manage members
+	RoleAdmin Role = "admin"
+	// RoleOwner is the list's owner and can also delete it. It is never stored on a membership.
+	RoleOwner Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// Valid reports whether the role can be given to a member
+func (r Role) Valid() bool {
+	return r == RoleViewer || r == RoleEditor || r == RoleAdmin
+}
+
+// AtLeast reports whether the role grants everything the other role does
+func (r Role) AtLeast(other Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[other]
+}
+
+// Membership gives a user other than the owner access to a list
+type Membership struct {
+	UserID  primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Role    Role               `json:"role" bson:"role"`
+	AddedAt time.Time          `json:"added_at" bson:"added_at"`
+}
+
+// RoleOf returns the user's role on the list, or "" if they have no access
+func (l *List) RoleOf(userID primitive.ObjectID) Role {
+	if l.UserID == userID {
+		return RoleOwner
+	}
+	for _, member := range l.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
 }
 
 // ListItem represents an item in a list
 type ListItem struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
 	Name     string             `json:"name" bson:"name"`
 	Quantity int                `json:"quantity" bson:"quantity"`
 	Checked  bool               `json:"checked" bson:"checked"`
@@ -49,27 +105,31 @@ type AddListItemRequest struct {
 
 // UpdateListItemCheckedRequest represents the request body for updating an item's checked state
 type UpdateListItemCheckedRequest struct {
-	Index   *int `json:"index" binding:"required"`
 	Checked bool `json:"checked"`
 }
 
 // UpdateListItemRequest represents the request body for updating an item's name, details, and quantity
 type UpdateListItemRequest struct {
-	Index    *int    `json:"index" binding:"required"`
 	Name     string  `json:"name,omitempty"`
 	Quantity *int    `json:"quantity,omitempty"`
-	Details  *string `json:"details,omitempty"`
+	Details  *string `json:"details,omitempty" binding:"omitempty,max=512"`
+}
+
+// UpdateMemberRoleRequest represents the request body for changing a member's role
+type UpdateMemberRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=viewer editor admin"`
 }
 
-// DeleteListItemRequest represents the request body for deleting an item from a list
-type DeleteListItemRequest struct {
-	Index *int `json:"index" binding:"required"`
+// TransferOwnershipRequest represents the request body for handing a list to another member
+type TransferOwnershipRequest struct {
+	UserID string `json:"user_id" binding:"required"`
 }
 
 // SharedUser represents a user that a list is shared with
 type SharedUser struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
+	Role  Role   `json:"role"`
 }
 
 // ListResponse represents the response for list operations
@@ -80,7 +140,8 @@ type ListResponse struct {
 	Description string       `json:"description,omitempty"`
 	Items       []ListItem   `json:"items"`
 	SharedWith  []SharedUser `json:"shared_with"`
+	Role        Role         `json:"role"` // The caller's role on the list
+	Version     int64        `json:"version"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
 }
-
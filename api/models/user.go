@@ -8,13 +8,15 @@ import (
 
 // User represents a user document in MongoDB
 type User struct {
-	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Email        string             `json:"email" bson:"email"`
-	PasswordHash string             `json:"-" bson:"password_hash"`
-	Username     string             `json:"username,omitempty" bson:"username,omitempty"`
-	Profile      *Profile           `json:"profile,omitempty" bson:"profile,omitempty"`
-	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email           string             `json:"email" bson:"email"`
+	PasswordHash    string             `json:"-" bson:"password_hash"`
+	EmailVerified   bool               `json:"email_verified" bson:"email_verified"`
+	EmailVerifiedAt *time.Time         `json:"email_verified_at,omitempty" bson:"email_verified_at,omitempty"`
+	Username        string             `json:"username,omitempty" bson:"username,omitempty"`
+	Profile         *Profile           `json:"profile,omitempty" bson:"profile,omitempty"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 // Profile represents user profile information
@@ -41,16 +43,42 @@ type SigninRequest struct {
 
 // AuthResponse represents the response for signup/signin
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  *UserPublic `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	User         *UserPublic `json:"user"`
 }
 
 // UserPublic represents public user information (without password)
 type UserPublic struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username,omitempty"`
-	Profile   *Profile  `json:"profile,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Username      string    `json:"username,omitempty"`
+	Profile       *Profile  `json:"profile,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
+// DeleteAccountRequest represents the request body for deleting the current user's account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UpdateProfileRequest represents the request body for updating the current user's profile
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty" binding:"omitempty,notblank"`
+	LastName  *string `json:"last_name,omitempty" binding:"omitempty,notblank"`
+	AvatarURL *string `json:"avatar_url,omitempty"`
+}
+
+// ChangePasswordRequest represents the request body for changing the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest represents the request body for changing the current user's email
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,432 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryUserStore is an in-memory UserStore for tests and local development
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+// NewMemoryUserStore creates an empty in-memory UserStore
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: make(map[primitive.ObjectID]models.User)}
+}
+
+// Create inserts a new user
+func (s *MemoryUserStore) Create(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email || existing.ID == user.ID {
+			return ErrDuplicate
+		}
+	}
+	s.users[user.ID] = copyUser(*user)
+	return nil
+}
+
+// FindByID returns the user with the given ID
+func (s *MemoryUserStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user = copyUser(user)
+	return &user, nil
+}
+
+// FindByEmail returns the user with the given email
+func (s *MemoryUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			user = copyUser(user)
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindByIDs returns the users with the given IDs
+func (s *MemoryUserStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []models.User{}
+	for _, id := range ids {
+		if user, ok := s.users[id]; ok {
+			users = append(users, copyUser(user))
+		}
+	}
+	return users, nil
+}
+
+// Update changes the user's fields
+func (s *MemoryUserStore) Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	// Mirror the unique indexes on email and username
+	for otherID, other := range s.users {
+		if otherID == id {
+			continue
+		}
+		if (update.Email != nil && other.Email == *update.Email) ||
+			(update.Username != nil && other.Username == *update.Username) {
+			return nil, ErrDuplicate
+		}
+	}
+
+	user = copyUser(user)
+	if update.Email != nil {
+		user.Email = *update.Email
+		user.EmailVerified = false
+		user.EmailVerifiedAt = nil
+	}
+	if update.Username != nil {
+		user.Username = *update.Username
+	}
+	if update.PasswordHash != nil {
+		user.PasswordHash = *update.PasswordHash
+	}
+	if update.Profile != nil {
+		profile := *update.Profile
+		user.Profile = &profile
+	}
+	user.UpdatedAt = time.Now()
+	s.users[id] = user
+
+	result := copyUser(user)
+	return &result, nil
+}
+
+// VerifyEmail marks the user's email as verified if it is still the given address
+func (s *MemoryUserStore) VerifyEmail(ctx context.Context, id primitive.ObjectID, email string, verifiedAt time.Time) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.Email != email {
+		return nil, ErrNotFound
+	}
+
+	user = copyUser(user)
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &verifiedAt
+	user.UpdatedAt = time.Now()
+	s.users[id] = user
+
+	result := copyUser(user)
+	return &result, nil
+}
+
+// Delete removes the user
+func (s *MemoryUserStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// MemoryListStore is an in-memory ListStore for tests and local development
+type MemoryListStore struct {
+	mu    sync.RWMutex
+	lists map[primitive.ObjectID]models.List
+}
+
+// NewMemoryListStore creates an empty in-memory ListStore
+func NewMemoryListStore() *MemoryListStore {
+	return &MemoryListStore{lists: make(map[primitive.ObjectID]models.List)}
+}
+
+// Create inserts a new list
+func (s *MemoryListStore) Create(ctx context.Context, list *models.List) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.lists[list.ID]; exists {
+		return ErrDuplicate
+	}
+	s.lists[list.ID] = copyList(*list)
+	return nil
+}
+
+// FindByID returns the list with the given ID
+func (s *MemoryListStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.List, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, ok := s.lists[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	list = copyList(list)
+	return &list, nil
+}
+
+// FindForUser returns the lists a user owns or is a member of, newest first
+func (s *MemoryListStore) FindForUser(ctx context.Context, userID primitive.ObjectID) ([]models.List, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lists := []models.List{}
+	for _, list := range s.lists {
+		if list.UserID == userID || memberIndex(&list, userID) >= 0 {
+			lists = append(lists, copyList(list))
+		}
+	}
+
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].CreatedAt.After(lists[j].CreatedAt)
+	})
+	return lists, nil
+}
+
+// Update changes the list's own fields
+func (s *MemoryListStore) Update(ctx context.Context, id primitive.ObjectID, version int64, update ListUpdate) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		if update.Name != nil {
+			list.Name = *update.Name
+		}
+		if update.Description != nil {
+			list.Description = *update.Description
+		}
+		return nil
+	})
+}
+
+// Delete removes the list
+func (s *MemoryListStore) Delete(ctx context.Context, id primitive.ObjectID, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, ok := s.lists[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != AnyVersion && list.Version != version {
+		return ErrVersionConflict
+	}
+	delete(s.lists, id)
+	return nil
+}
+
+// AddItem appends an item to the list
+func (s *MemoryListStore) AddItem(ctx context.Context, id primitive.ObjectID, version int64, item models.ListItem) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		list.Items = append(list.Items, item)
+		return nil
+	})
+}
+
+// UpdateItem changes a single item
+func (s *MemoryListStore) UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, version int64, update ItemUpdate) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		index := itemIndex(list, itemID)
+		if index < 0 {
+			return ErrItemNotFound
+		}
+
+		item := &list.Items[index]
+		if update.Name != nil {
+			item.Name = *update.Name
+		}
+		if update.Quantity != nil {
+			item.Quantity = *update.Quantity
+		}
+		if update.Details != nil {
+			item.Details = *update.Details
+		}
+		if update.Checked != nil {
+			item.Checked = *update.Checked
+		}
+		return nil
+	})
+}
+
+// DeleteItem removes a single item from the list
+func (s *MemoryListStore) DeleteItem(ctx context.Context, id, itemID primitive.ObjectID, version int64) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		index := itemIndex(list, itemID)
+		if index < 0 {
+			return ErrItemNotFound
+		}
+		list.Items = append(list.Items[:index], list.Items[index+1:]...)
+		return nil
+	})
+}
+
+// AddMember adds a membership unless the user is already a member
+func (s *MemoryListStore) AddMember(ctx context.Context, id primitive.ObjectID, member models.Membership) (*models.List, error) {
+	return s.write(id, AnyVersion, func(list *models.List) error {
+		if memberIndex(list, member.UserID) >= 0 {
+			return ErrDuplicate
+		}
+		list.Members = append(list.Members, member)
+		return nil
+	})
+}
+
+// UpdateMemberRole changes a member's role
+func (s *MemoryListStore) UpdateMemberRole(ctx context.Context, id, userID primitive.ObjectID, version int64, role models.Role) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		index := memberIndex(list, userID)
+		if index < 0 {
+			return ErrMemberNotFound
+		}
+		list.Members[index].Role = role
+		return nil
+	})
+}
+
+// RemoveMember removes a member from the list
+func (s *MemoryListStore) RemoveMember(ctx context.Context, id, userID primitive.ObjectID, version int64) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		index := memberIndex(list, userID)
+		if index < 0 {
+			return ErrMemberNotFound
+		}
+		list.Members = append(list.Members[:index], list.Members[index+1:]...)
+		if list.Removed == nil {
+			list.Removed = make(map[string]time.Time)
+		}
+		list.Removed[userID.Hex()] = time.Now()
+		return nil
+	})
+}
+
+// TransferOwnership swaps the owner and the member under a single lock
+func (s *MemoryListStore) TransferOwnership(ctx context.Context, id, currentOwnerID, newOwnerID primitive.ObjectID, version int64, formerOwnerRole models.Role) (*models.List, error) {
+	return s.write(id, version, func(list *models.List) error {
+		if list.UserID != currentOwnerID {
+			return ErrVersionConflict
+		}
+		index := memberIndex(list, newOwnerID)
+		if index < 0 {
+			return ErrMemberNotFound
+		}
+		list.UserID = newOwnerID
+		list.Members[index] = models.Membership{
+			UserID:  currentOwnerID,
+			Role:    formerOwnerRole,
+			AddedAt: time.Now(),
+		}
+		return nil
+	})
+}
+
+// AnonymizeItems clears AddedBy on the user's items in every list that has any
+func (s *MemoryListStore) AnonymizeItems(ctx context.Context, userID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, stored := range s.lists {
+		list := copyList(stored)
+		changed := false
+		for i := range list.Items {
+			if list.Items[i].AddedBy == userID {
+				list.Items[i].AddedBy = primitive.NilObjectID
+				changed = true
+			}
+		}
+		if changed {
+			list.Version++
+			list.UpdatedAt = now
+			s.lists[id] = list
+		}
+	}
+	return nil
+}
+
+// write applies a change under the lock, bumps the version and returns a copy of the result
+func (s *MemoryListStore) write(id primitive.ObjectID, version int64, apply func(list *models.List) error) (*models.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.lists[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if version != AnyVersion && stored.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	list := copyList(stored)
+	if err := apply(&list); err != nil {
+		return nil, err
+	}
+	list.Version++
+	list.UpdatedAt = time.Now()
+	s.lists[id] = list
+
+	result := copyList(list)
+	return &result, nil
+}
+
+func itemIndex(list *models.List, itemID primitive.ObjectID) int {
+	for i, item := range list.Items {
+		if item.ID == itemID {
+			return i
+		}
+	}
+	return -1
+}
+
+func memberIndex(list *models.List, userID primitive.ObjectID) int {
+	for i, member := range list.Members {
+		if member.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyList returns a deep copy so callers can't mutate stored state
+func copyList(list models.List) models.List {
+	list.Items = append([]models.ListItem{}, list.Items...)
+	list.Members = append([]models.Membership{}, list.Members...)
+	if list.Removed != nil {
+		list.Removed = maps.Clone(list.Removed)
+	}
+	return list
+}
+
+// copyUser returns a deep copy so callers can't mutate stored state
+func copyUser(user models.User) models.User {
+	if user.Profile != nil {
+		profile := *user.Profile
+		user.Profile = &profile
+	}
+	if user.EmailVerifiedAt != nil {
+		verifiedAt := *user.EmailVerifiedAt
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return user
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryInviteStore is an in-memory InviteStore for tests and local development
+type MemoryInviteStore struct {
+	mu      sync.Mutex
+	invites map[primitive.ObjectID]models.Invite
+}
+
+// NewMemoryInviteStore creates an empty in-memory InviteStore
+func NewMemoryInviteStore() *MemoryInviteStore {
+	return &MemoryInviteStore{invites: make(map[primitive.ObjectID]models.Invite)}
+}
+
+// Create stores a new invite
+func (s *MemoryInviteStore) Create(ctx context.Context, invite *models.Invite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.invites[invite.ID]; exists {
+		return ErrDuplicate
+	}
+	s.invites[invite.ID] = *invite
+	return nil
+}
+
+// FindByID returns the invite with the given ID
+func (s *MemoryInviteStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &invite, nil
+}
+
+// FindActiveForList returns the list's usable invites, newest first
+func (s *MemoryInviteStore) FindActiveForList(ctx context.Context, listID primitive.ObjectID, now time.Time) ([]models.Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invites := []models.Invite{}
+	for _, invite := range s.invites {
+		if invite.ListID == listID && inviteActive(invite, now) {
+			invites = append(invites, invite)
+		}
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.After(invites[j].CreatedAt)
+	})
+	return invites, nil
+}
+
+// Consume atomically records one use of an invite
+func (s *MemoryInviteStore) Consume(ctx context.Context, id primitive.ObjectID, now time.Time) (*models.Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[id]
+	if !ok || !inviteActive(invite, now) {
+		return nil, ErrTokenUsed
+	}
+	invite.Uses++
+	s.invites[id] = invite
+	return &invite, nil
+}
+
+// Release gives back a use recorded by Consume
+func (s *MemoryInviteStore) Release(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[id]
+	if !ok || invite.Uses == 0 {
+		return ErrNotFound
+	}
+	invite.Uses--
+	s.invites[id] = invite
+	return nil
+}
+
+// Revoke revokes an invite
+func (s *MemoryInviteStore) Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[id]
+	if !ok || invite.RevokedAt != nil {
+		return ErrNotFound
+	}
+	invite.RevokedAt = &revokedAt
+	s.invites[id] = invite
+	return nil
+}
+
+// RevokeCreatedBy revokes every unrevoked invite the user created
+func (s *MemoryInviteStore) RevokeCreatedBy(ctx context.Context, userID primitive.ObjectID, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, invite := range s.invites {
+		if invite.CreatedBy == userID && invite.RevokedAt == nil {
+			invite.RevokedAt = &revokedAt
+			s.invites[id] = invite
+		}
+	}
+	return nil
+}
+
+// RevokeCreatedByOnList revokes every unrevoked invite the user created for the list
+func (s *MemoryInviteStore) RevokeCreatedByOnList(ctx context.Context, listID, userID primitive.ObjectID, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, invite := range s.invites {
+		if invite.ListID == listID && invite.CreatedBy == userID && invite.RevokedAt == nil {
+			invite.RevokedAt = &revokedAt
+			s.invites[id] = invite
+		}
+	}
+	return nil
+}
+
+// inviteActive reports whether an invite can still be used
+func inviteActive(invite models.Invite, now time.Time) bool {
+	return invite.RevokedAt == nil && now.Before(invite.ExpiresAt) && invite.Uses < invite.MaxUses
+}
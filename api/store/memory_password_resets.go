@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryPasswordResetStore is an in-memory PasswordResetStore for tests and local development
+type MemoryPasswordResetStore struct {
+	mu     sync.Mutex
+	tokens map[primitive.ObjectID]models.PasswordResetToken
+}
+
+// NewMemoryPasswordResetStore creates an empty in-memory PasswordResetStore
+func NewMemoryPasswordResetStore() *MemoryPasswordResetStore {
+	return &MemoryPasswordResetStore{tokens: make(map[primitive.ObjectID]models.PasswordResetToken)}
+}
+
+// Create stores a new reset token
+func (s *MemoryPasswordResetStore) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.tokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	s.tokens[token.ID] = *token
+	return nil
+}
+
+// FindByHash returns the reset token with the given hash
+func (s *MemoryPasswordResetStore) FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// MarkUsed atomically marks a token as used
+func (s *MemoryPasswordResetStore) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return ErrTokenUsed
+	}
+	token.UsedAt = &usedAt
+	s.tokens[id] = token
+	return nil
+}
+
+// InvalidateUser marks every unused token belonging to a user as used
+func (s *MemoryPasswordResetStore) InvalidateUser(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			s.tokens[id] = token
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryRateLimitStore is an in-memory RateLimitStore for tests and single-instance deployments
+type MemoryRateLimitStore struct {
+	mu       sync.Mutex
+	counters map[string]rateLimitCounter
+	sweptAt  time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory RateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counters: make(map[string]rateLimitCounter)}
+}
+
+// Increment counts a hit under the lock
+func (s *MemoryRateLimitStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.ResetAt) {
+		counter = rateLimitCounter{Key: key, ResetAt: now.Add(window)}
+	}
+	counter.Count++
+	s.counters[key] = counter
+
+	return counter.Count, counter.ResetAt, nil
+}
+
+// Peek returns the key's count without changing it
+func (s *MemoryRateLimitStore) Peek(ctx context.Context, key string, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.ResetAt) {
+		return 0, time.Time{}, nil
+	}
+	return counter.Count, counter.ResetAt, nil
+}
+
+// Reset forgets the key's counter
+func (s *MemoryRateLimitStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	return nil
+}
+
+// sweep drops ended counters about once a minute so the map doesn't grow forever,
+// like the TTL index does for the Mongo store. Callers must hold s.mu.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < time.Minute {
+		return
+	}
+	s.sweptAt = now
+
+	for key, counter := range s.counters {
+		if !now.Before(counter.ResetAt) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryRevocationStore is an in-memory RevocationStore for tests and local development
+type MemoryRevocationStore struct {
+	mu            sync.RWMutex
+	tokens        map[string]time.Time
+	revokedBefore map[primitive.ObjectID]time.Time
+}
+
+// NewMemoryRevocationStore creates an empty in-memory RevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:        make(map[string]time.Time),
+		revokedBefore: make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// RevokeToken revokes a single access token
+func (s *MemoryRevocationStore) RevokeToken(ctx context.Context, tokenID string, userID primitive.ObjectID, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop entries for tokens that have expired on their own
+	now := time.Now()
+	for id, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+// RevokeAllBefore revokes every access token issued to the user before the given time
+func (s *MemoryRevocationStore) RevokeAllBefore(ctx context.Context, userID primitive.ObjectID, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if before.After(s.revokedBefore[userID]) {
+		s.revokedBefore[userID] = before
+	}
+	return nil
+}
+
+// IsRevoked reports whether a token was revoked
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, tokenID string, userID primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[tokenID]; ok && tokenID != "" {
+		return true, nil
+	}
+	return issuedAt.Before(s.revokedBefore[userID]), nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryRefreshTokenStore is an in-memory RefreshTokenStore for tests and local development
+type MemoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[primitive.ObjectID]models.RefreshToken
+}
+
+// NewMemoryRefreshTokenStore creates an empty in-memory RefreshTokenStore
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{tokens: make(map[primitive.ObjectID]models.RefreshToken)}
+}
+
+// Create stores a new refresh token
+func (s *MemoryRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.tokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	s.tokens[token.ID] = *token
+	return nil
+}
+
+// FindByHash returns the refresh token with the given hash
+func (s *MemoryRefreshTokenStore) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// MarkUsed atomically marks a token as used
+func (s *MemoryRefreshTokenStore) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return ErrTokenUsed
+	}
+	token.UsedAt = &usedAt
+	s.tokens[id] = token
+	return nil
+}
+
+// RevokeFamily revokes every token in a family
+func (s *MemoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyID primitive.ObjectID, revokedAt time.Time) error {
+	s.revoke(func(token models.RefreshToken) bool { return token.FamilyID == familyID }, revokedAt)
+	return nil
+}
+
+// RevokeUser revokes every token belonging to a user
+func (s *MemoryRefreshTokenStore) RevokeUser(ctx context.Context, userID primitive.ObjectID, revokedAt time.Time) error {
+	s.revoke(func(token models.RefreshToken) bool { return token.UserID == userID }, revokedAt)
+	return nil
+}
+
+func (s *MemoryRefreshTokenStore) revoke(match func(token models.RefreshToken) bool, revokedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.tokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			s.tokens[id] = token
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoUserStore is a UserStore backed by the "users" collection
+type MongoUserStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserStore creates a UserStore for the given database
+func NewMongoUserStore(db *mongo.Database) *MongoUserStore {
+	return &MongoUserStore{collection: db.Collection("users")}
+}
+
+// Create inserts a new user
+func (s *MongoUserStore) Create(ctx context.Context, user *models.User) error {
+	_, err := s.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// FindByID returns the user with the given ID
+func (s *MongoUserStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindByEmail returns the user with the given email
+func (s *MongoUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.findOne(ctx, bson.M{"email": email})
+}
+
+// FindByIDs returns the users with the given IDs
+func (s *MongoUserStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Update changes the user's fields
+func (s *MongoUserStore) Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error) {
+	set := bson.M{"updated_at": time.Now()}
+	changes := bson.M{"$set": set}
+	if update.Email != nil {
+		set["email"] = *update.Email
+		set["email_verified"] = false
+		changes["$unset"] = bson.M{"email_verified_at": ""}
+	}
+	if update.Username != nil {
+		set["username"] = *update.Username
+	}
+	if update.PasswordHash != nil {
+		set["password_hash"] = *update.PasswordHash
+	}
+	if update.Profile != nil {
+		set["profile"] = update.Profile
+	}
+
+	return s.findOneAndUpdate(ctx, bson.M{"_id": id}, changes)
+}
+
+// VerifyEmail marks the user's email as verified if it is still the given address
+func (s *MongoUserStore) VerifyEmail(ctx context.Context, id primitive.ObjectID, email string, verifiedAt time.Time) (*models.User, error) {
+	update := bson.M{"$set": bson.M{
+		"email_verified":    true,
+		"email_verified_at": verifiedAt,
+		"updated_at":        time.Now(),
+	}}
+	return s.findOneAndUpdate(ctx, bson.M{"_id": id, "email": email}, update)
+}
+
+// Delete removes the user
+func (s *MongoUserStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// findOneAndUpdate applies an update and returns the user as it is afterwards
+func (s *MongoUserStore) findOneAndUpdate(ctx context.Context, filter, update bson.M) (*models.User, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user models.User
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *MongoUserStore) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	err := s.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// MongoListStore is a ListStore backed by the "lists" collection
+type MongoListStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoListStore creates a ListStore for the given database
+func NewMongoListStore(db *mongo.Database) *MongoListStore {
+	return &MongoListStore{collection: db.Collection("lists")}
+}
+
+// Create inserts a new list
+func (s *MongoListStore) Create(ctx context.Context, list *models.List) error {
+	_, err := s.collection.InsertOne(ctx, list)
+	return err
+}
+
+// FindByID returns the list with the given ID
+func (s *MongoListStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.List, error) {
+	var list models.List
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&list)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// FindForUser returns the lists a user owns or is a member of, newest first
+func (s *MongoListStore) FindForUser(ctx context.Context, userID primitive.ObjectID) ([]models.List, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"user_id": userID},
+			{"members.user_id": userID},
+		},
+	}
+
+	// Sort by created_at descending
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	lists := []models.List{}
+	if err := cursor.All(ctx, &lists); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// Update changes the list's own fields
+func (s *MongoListStore) Update(ctx context.Context, id primitive.ObjectID, version int64, update ListUpdate) (*models.List, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+
+	return s.write(ctx, versionFilter(id, version), bson.M{"$set": set}, id, version, nil)
+}
+
+// Delete removes the list
+func (s *MongoListStore) Delete(ctx context.Context, id primitive.ObjectID, version int64) error {
+	result, err := s.collection.DeleteOne(ctx, versionFilter(id, version))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		_, err := s.explainMiss(ctx, id, version, nil)
+		return err
+	}
+	return nil
+}
+
+// AddItem appends an item to the list
+func (s *MongoListStore) AddItem(ctx context.Context, id primitive.ObjectID, version int64, item models.ListItem) (*models.List, error) {
+	update := bson.M{
+		"$push": bson.M{"items": item},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	return s.write(ctx, versionFilter(id, version), update, id, version, nil)
+}
+
+// UpdateItem changes only the matched item so concurrent edits to other items are kept
+func (s *MongoListStore) UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, version int64, update ItemUpdate) (*models.List, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if update.Name != nil {
+		set["items.$.name"] = *update.Name
+	}
+	if update.Quantity != nil {
+		set["items.$.quantity"] = *update.Quantity
+	}
+	if update.Details != nil {
+		set["items.$.details"] = *update.Details
+	}
+	if update.Checked != nil {
+		set["items.$.checked"] = *update.Checked
+	}
+
+	filter := versionFilter(id, version)
+	filter["items._id"] = itemID
+	return s.write(ctx, filter, bson.M{"$set": set}, id, version, ErrItemNotFound)
+}
+
+// DeleteItem removes a single item from the list
+func (s *MongoListStore) DeleteItem(ctx context.Context, id, itemID primitive.ObjectID, version int64) (*models.List, error) {
+	update := bson.M{
+		"$pull": bson.M{"items": bson.M{"_id": itemID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	filter := versionFilter(id, version)
+	filter["items._id"] = itemID
+	return s.write(ctx, filter, update, id, version, ErrItemNotFound)
+}
+
+// AddMember adds a membership unless the user is already a member
+func (s *MongoListStore) AddMember(ctx context.Context, id primitive.ObjectID, member models.Membership) (*models.List, error) {
+	update := bson.M{
+		"$push": bson.M{"members": member},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	filter := bson.M{"_id": id, "members.user_id": bson.M{"$ne": member.UserID}}
+	return s.write(ctx, filter, update, id, AnyVersion, ErrDuplicate)
+}
+
+// UpdateMemberRole changes only the matched membership
+func (s *MongoListStore) UpdateMemberRole(ctx context.Context, id, userID primitive.ObjectID, version int64, role models.Role) (*models.List, error) {
+	update := bson.M{
+		"$set": bson.M{"members.$.role": role, "updated_at": time.Now()},
+	}
+
+	filter := versionFilter(id, version)
+	filter["members.user_id"] = userID
+	return s.write(ctx, filter, update, id, version, ErrMemberNotFound)
+}
+
+// RemoveMember pulls the user's membership from the list and records when
+func (s *MongoListStore) RemoveMember(ctx context.Context, id, userID primitive.ObjectID, version int64) (*models.List, error) {
+	now := time.Now()
+	update := bson.M{
+		"$pull": bson.M{"members": bson.M{"user_id": userID}},
+		"$set":  bson.M{"removed." + userID.Hex(): now, "updated_at": now},
+	}
+
+	filter := versionFilter(id, version)
+	filter["members.user_id"] = userID
+	return s.write(ctx, filter, update, id, version, ErrMemberNotFound)
+}
+
+// TransferOwnership swaps the owner and the member in place so the list always has exactly one owner
+func (s *MongoListStore) TransferOwnership(ctx context.Context, id, currentOwnerID, newOwnerID primitive.ObjectID, version int64, formerOwnerRole models.Role) (*models.List, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"user_id":            newOwnerID,
+			"members.$.user_id":  currentOwnerID,
+			"members.$.role":     formerOwnerRole,
+			"members.$.added_at": now,
+			"updated_at":         now,
+		},
+	}
+
+	filter := versionFilter(id, version)
+	filter["user_id"] = currentOwnerID
+	filter["members.user_id"] = newOwnerID
+
+	list, err := s.write(ctx, filter, update, id, version, ErrMemberNotFound)
+	if errors.Is(err, ErrMemberNotFound) {
+		// Someone else may have transferred the list first
+		if current, findErr := s.FindByID(ctx, id); findErr == nil && current.UserID != currentOwnerID {
+			return nil, ErrVersionConflict
+		}
+	}
+	return list, err
+}
+
+// AnonymizeItems clears AddedBy on the user's items in every list that has any
+func (s *MongoListStore) AnonymizeItems(ctx context.Context, userID primitive.ObjectID) error {
+	update := bson.M{
+		"$set": bson.M{
+			"items.$[item].added_by": primitive.NilObjectID,
+			"updated_at":             time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"item.added_by": userID}})
+
+	_, err := s.collection.UpdateMany(ctx, bson.M{"items.added_by": userID}, update, opts)
+	return err
+}
+
+// write applies an update, bumps the version and returns the list as it is afterwards.
+// missErr is reported when the list is at the expected version but the rest of the filter didn't match.
+func (s *MongoListStore) write(ctx context.Context, filter, update bson.M, id primitive.ObjectID, version int64, missErr error) (*models.List, error) {
+	update["$inc"] = bson.M{"version": 1}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var list models.List
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&list)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return s.explainMiss(ctx, id, version, missErr)
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+// explainMiss works out why a write matched no document
+func (s *MongoListStore) explainMiss(ctx context.Context, id primitive.ObjectID, version int64, missErr error) (*models.List, error) {
+	list, err := s.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != AnyVersion && list.Version != version {
+		return nil, ErrVersionConflict
+	}
+	if missErr != nil {
+		return nil, missErr
+	}
+	// The list changed between the write and this lookup; report it as a conflict
+	return nil, ErrVersionConflict
+}
+
+// versionFilter matches the list, pinned to a version unless AnyVersion is given.
+// Version 0 also matches lists that have no version field yet.
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	filter := bson.M{"_id": id}
+	switch version {
+	case AnyVersion:
+	case 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	default:
+		filter["version"] = version
+	}
+	return filter
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoInviteStore is an InviteStore backed by the "invites" collection
+type MongoInviteStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoInviteStore creates an InviteStore for the given database
+func NewMongoInviteStore(db *mongo.Database) *MongoInviteStore {
+	return &MongoInviteStore{collection: db.Collection("invites")}
+}
+
+// Create stores a new invite
+func (s *MongoInviteStore) Create(ctx context.Context, invite *models.Invite) error {
+	_, err := s.collection.InsertOne(ctx, invite)
+	return err
+}
+
+// FindByID returns the invite with the given ID
+func (s *MongoInviteStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invite, error) {
+	var invite models.Invite
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&invite)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// FindActiveForList returns the list's usable invites, newest first
+func (s *MongoInviteStore) FindActiveForList(ctx context.Context, listID primitive.ObjectID, now time.Time) ([]models.Invite, error) {
+	filter := activeInviteFilter(now)
+	filter["list_id"] = listID
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invites := []models.Invite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// Consume atomically records one use of an invite
+func (s *MongoInviteStore) Consume(ctx context.Context, id primitive.ObjectID, now time.Time) (*models.Invite, error) {
+	filter := activeInviteFilter(now)
+	filter["_id"] = id
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var invite models.Invite
+	err := s.collection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}}, opts).Decode(&invite)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTokenUsed
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// Release gives back a use recorded by Consume
+func (s *MongoInviteStore) Release(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "uses": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"uses": -1}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Revoke revokes an invite
+func (s *MongoInviteStore) Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error {
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeCreatedBy revokes every unrevoked invite the user created
+func (s *MongoInviteStore) RevokeCreatedBy(ctx context.Context, userID primitive.ObjectID, revokedAt time.Time) error {
+	_, err := s.collection.UpdateMany(
+		ctx,
+		bson.M{"created_by": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	return err
+}
+
+// RevokeCreatedByOnList revokes every unrevoked invite the user created for the list
+func (s *MongoInviteStore) RevokeCreatedByOnList(ctx context.Context, listID, userID primitive.ObjectID, revokedAt time.Time) error {
+	_, err := s.collection.UpdateMany(
+		ctx,
+		bson.M{"list_id": listID, "created_by": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	return err
+}
+
+// activeInviteFilter matches invites that can still be used
+func activeInviteFilter(now time.Time) bson.M {
+	return bson.M{
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+		"$expr":      bson.M{"$lt": bson.A{"$uses", "$max_uses"}},
+	}
+}
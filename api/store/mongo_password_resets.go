@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MongoPasswordResetStore is a PasswordResetStore backed by the "password_resets" collection
+type MongoPasswordResetStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPasswordResetStore creates a PasswordResetStore for the given database
+func NewMongoPasswordResetStore(db *mongo.Database) *MongoPasswordResetStore {
+	return &MongoPasswordResetStore{collection: db.Collection("password_resets")}
+}
+
+// Create stores a new reset token
+func (s *MongoPasswordResetStore) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	_, err := s.collection.InsertOne(ctx, token)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// FindByHash returns the reset token with the given hash
+func (s *MongoPasswordResetStore) FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := s.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed atomically marks a token as used
+func (s *MongoPasswordResetStore) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": usedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrTokenUsed
+	}
+	return nil
+}
+
+// InvalidateUser marks every unused token belonging to a user as used
+func (s *MongoPasswordResetStore) InvalidateUser(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error {
+	_, err := s.collection.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": usedAt}},
+	)
+	return err
+}
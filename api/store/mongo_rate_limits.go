@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// rateLimitCounter is a counter document in the "rate_limits" collection
+type rateLimitCounter struct {
+	Key     string    `bson:"_id"`
+	Count   int       `bson:"count"`
+	ResetAt time.Time `bson:"reset_at"`
+}
+
+// MongoRateLimitStore is a RateLimitStore backed by the "rate_limits" collection,
+// so limits hold across every API instance
+type MongoRateLimitStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRateLimitStore creates a RateLimitStore for the given database
+func NewMongoRateLimitStore(db *mongo.Database) *MongoRateLimitStore {
+	return &MongoRateLimitStore{collection: db.Collection("rate_limits")}
+}
+
+// Increment counts a hit in a single atomic upsert
+func (s *MongoRateLimitStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	// Both fields are computed from the document as it was, so an ended window restarts as a whole
+	active := bson.M{"$gt": bson.A{"$reset_at", now}}
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"count":    bson.M{"$cond": bson.A{active, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+			"reset_at": bson.M{"$cond": bson.A{active, "$reset_at", now.Add(window)}},
+		}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter rateLimitCounter
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		// Lost a race to create the counter; it exists now
+		err = s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&counter)
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return counter.Count, counter.ResetAt, nil
+}
+
+// Peek returns the key's count without changing it
+func (s *MongoRateLimitStore) Peek(ctx context.Context, key string, now time.Time) (int, time.Time, error) {
+	var counter rateLimitCounter
+	err := s.collection.FindOne(ctx, bson.M{"_id": key, "reset_at": bson.M{"$gt": now}}).Decode(&counter)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, err
+	}
+	return counter.Count, counter.ResetAt, nil
+}
+
+// Reset deletes the key's counter
+func (s *MongoRateLimitStore) Reset(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoRevocationStore is a RevocationStore backed by the "revoked_tokens" and
+// "user_revocations" collections
+type MongoRevocationStore struct {
+	tokens *mongo.Collection
+	users  *mongo.Collection
+}
+
+// NewMongoRevocationStore creates a RevocationStore for the given database
+func NewMongoRevocationStore(db *mongo.Database) *MongoRevocationStore {
+	return &MongoRevocationStore{
+		tokens: db.Collection("revoked_tokens"),
+		users:  db.Collection("user_revocations"),
+	}
+}
+
+// RevokeToken revokes a single access token
+func (s *MongoRevocationStore) RevokeToken(ctx context.Context, tokenID string, userID primitive.ObjectID, expiresAt time.Time) error {
+	_, err := s.tokens.UpdateOne(
+		ctx,
+		bson.M{"_id": tokenID},
+		bson.M{"$set": bson.M{
+			"user_id":    userID,
+			"expires_at": expiresAt,
+		}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// RevokeAllBefore revokes every access token issued to the user before the given time
+func (s *MongoRevocationStore) RevokeAllBefore(ctx context.Context, userID primitive.ObjectID, before time.Time) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$max": bson.M{"revoked_before": before}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// IsRevoked reports whether a token was revoked
+func (s *MongoRevocationStore) IsRevoked(ctx context.Context, tokenID string, userID primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	if tokenID != "" {
+		err := s.tokens.FindOne(ctx, bson.M{"_id": tokenID}).Err()
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return false, err
+		}
+	}
+
+	var revocation struct {
+		RevokedBefore time.Time `bson:"revoked_before"`
+	}
+	err := s.users.FindOne(ctx, bson.M{"_id": userID}).Decode(&revocation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, err
+	}
+	return issuedAt.Before(revocation.RevokedBefore), nil
+}
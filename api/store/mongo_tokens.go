@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MongoRefreshTokenStore is a RefreshTokenStore backed by the "refresh_tokens" collection
+type MongoRefreshTokenStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRefreshTokenStore creates a RefreshTokenStore for the given database
+func NewMongoRefreshTokenStore(db *mongo.Database) *MongoRefreshTokenStore {
+	return &MongoRefreshTokenStore{collection: db.Collection("refresh_tokens")}
+}
+
+// Create stores a new refresh token
+func (s *MongoRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	_, err := s.collection.InsertOne(ctx, token)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// FindByHash returns the refresh token with the given hash
+func (s *MongoRefreshTokenStore) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := s.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed atomically marks a token as used
+func (s *MongoRefreshTokenStore) MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":        id,
+			"used_at":    bson.M{"$exists": false},
+			"revoked_at": bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{"used_at": usedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrTokenUsed
+	}
+	return nil
+}
+
+// RevokeFamily revokes every token in a family
+func (s *MongoRefreshTokenStore) RevokeFamily(ctx context.Context, familyID primitive.ObjectID, revokedAt time.Time) error {
+	return s.revoke(ctx, bson.M{"family_id": familyID}, revokedAt)
+}
+
+// RevokeUser revokes every token belonging to a user
+func (s *MongoRefreshTokenStore) RevokeUser(ctx context.Context, userID primitive.ObjectID, revokedAt time.Time) error {
+	return s.revoke(ctx, bson.M{"user_id": userID}, revokedAt)
+}
+
+func (s *MongoRefreshTokenStore) revoke(ctx context.Context, filter bson.M, revokedAt time.Time) error {
+	filter["revoked_at"] = bson.M{"$exists": false}
+	_, err := s.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": revokedAt}})
+	return err
+}
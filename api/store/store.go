@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bryce-stabenow/grocer-me/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when the requested document does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrItemNotFound is returned when a list exists but the requested item does not
+	ErrItemNotFound = errors.New("item not found")
+	// ErrDuplicate is returned when a write would violate a unique constraint
+	ErrDuplicate = errors.New("duplicate")
+	// ErrMemberNotFound is returned when a list exists but the user is not a member of it
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrVersionConflict is returned when a write expected a list version that is no longer current
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrTokenUsed is returned when a token has already been used up, expired or been revoked
+	ErrTokenUsed = errors.New("token already used")
+)
+
+// AnyVersion can be passed as the expected version to skip the optimistic concurrency check.
+// It is negative because 0 is the version of lists created before versioning.
+const AnyVersion int64 = -1
+
+// UserStore persists users
+type UserStore interface {
+	// Create inserts a new user. Returns ErrDuplicate if the email is taken.
+	Create(ctx context.Context, user *models.User) error
+	// FindByID returns the user with the given ID
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	// FindByEmail returns the user with the given email
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// FindByIDs returns the users with the given IDs. Missing users are skipped.
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error)
+	// Update changes the user's fields and returns the updated user.
+	// Returns ErrDuplicate if the new email or username is taken.
+	Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error)
+	// VerifyEmail marks the user's email as verified, as long as it is still the given address.
+	// Returns ErrNotFound if the user doesn't exist or their email has since changed.
+	VerifyEmail(ctx context.Context, id primitive.ObjectID, email string, verifiedAt time.Time) (*models.User, error)
+	// Delete removes the user
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// UserUpdate holds the user fields to change. Nil fields are left untouched.
+// Changing the email also marks it unverified.
+type UserUpdate struct {
+	Email        *string
+	Username     *string
+	PasswordHash *string
+	Profile      *models.Profile
+}
+
+// ListUpdate holds the list fields to change. Nil fields are left untouched.
+type ListUpdate struct {
+	Name        *string
+	Description *string
+}
+
+// ItemUpdate holds the item fields to change. Nil fields are left untouched.
+type ItemUpdate struct {
+	Name     *string
+	Quantity *int
+	Details  *string
+	Checked  *bool
+}
+
+// ListStore persists lists and their items.
+//
+// Every write bumps the list version. Writes that take an expected version fail with
+// ErrVersionConflict when the list is no longer at that version, unless AnyVersion is given.
+type ListStore interface {
+	// Create inserts a new list
+	Create(ctx context.Context, list *models.List) error
+	// FindByID returns the list with the given ID
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.List, error)
+	// FindForUser returns the lists a user owns or is a member of, newest first
+	FindForUser(ctx context.Context, userID primitive.ObjectID) ([]models.List, error)
+	// Update changes the list's own fields and returns the updated list
+	Update(ctx context.Context, id primitive.ObjectID, version int64, update ListUpdate) (*models.List, error)
+	// Delete removes the list
+	Delete(ctx context.Context, id primitive.ObjectID, version int64) error
+	// AddItem appends an item and returns the updated list
+	AddItem(ctx context.Context, id primitive.ObjectID, version int64, item models.ListItem) (*models.List, error)
+	// UpdateItem changes a single item and returns the updated list
+	UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, version int64, update ItemUpdate) (*models.List, error)
+	// DeleteItem removes a single item and returns the updated list
+	DeleteItem(ctx context.Context, id, itemID primitive.ObjectID, version int64) (*models.List, error)
+	// AddMember adds a membership and returns the updated list. Returns ErrDuplicate if the user is already a member.
+	AddMember(ctx context.Context, id primitive.ObjectID, member models.Membership) (*models.List, error)
+	// UpdateMemberRole changes a member's role and returns the updated list
+	UpdateMemberRole(ctx context.Context, id, userID primitive.ObjectID, version int64, role models.Role) (*models.List, error)
+	// RemoveMember removes a member, records when in Removed and returns the updated list
+	RemoveMember(ctx context.Context, id, userID primitive.ObjectID, version int64) (*models.List, error)
+	// TransferOwnership makes the member newOwnerID the owner and turns the current owner into a
+	// member with the given role, in a single write. Returns ErrMemberNotFound if newOwnerID is not
+	// a member and ErrVersionConflict if currentOwnerID no longer owns the list.
+	TransferOwnership(ctx context.Context, id, currentOwnerID, newOwnerID primitive.ObjectID, version int64, formerOwnerRole models.Role) (*models.List, error)
+	// AnonymizeItems clears AddedBy on every item the user added, across all lists
+	AnonymizeItems(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// RefreshTokenStore persists refresh tokens
+type RefreshTokenStore interface {
+	// Create stores a new refresh token
+	Create(ctx context.Context, token *models.RefreshToken) error
+	// FindByHash returns the refresh token with the given hash
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// MarkUsed atomically marks a token as used. Returns ErrTokenUsed if it was
+	// already used or revoked, so only one caller can rotate a given token.
+	MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+	// RevokeFamily revokes every token descended from the same sign-in
+	RevokeFamily(ctx context.Context, familyID primitive.ObjectID, revokedAt time.Time) error
+	// RevokeUser revokes every token belonging to a user
+	RevokeUser(ctx context.Context, userID primitive.ObjectID, revokedAt time.Time) error
+}
+
+// PasswordResetStore persists password reset tokens
+type PasswordResetStore interface {
+	// Create stores a new reset token
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	// FindByHash returns the reset token with the given hash
+	FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	// MarkUsed atomically marks a token as used. Returns ErrTokenUsed if it was already used.
+	MarkUsed(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+	// InvalidateUser marks every unused token belonging to a user as used
+	InvalidateUser(ctx context.Context, userID primitive.ObjectID, usedAt time.Time) error
+}
+
+// RevocationStore tracks access tokens that were invalidated before they expired
+type RevocationStore interface {
+	// RevokeToken revokes a single access token by its ID (jti) until it would have expired anyway
+	RevokeToken(ctx context.Context, tokenID string, userID primitive.ObjectID, expiresAt time.Time) error
+	// RevokeAllBefore revokes every access token issued to the user before the given time
+	RevokeAllBefore(ctx context.Context, userID primitive.ObjectID, before time.Time) error
+	// IsRevoked reports whether a token was revoked on its own or by a user-wide revocation
+	IsRevoked(ctx context.Context, tokenID string, userID primitive.ObjectID, issuedAt time.Time) (bool, error)
+}
+
+// InviteStore persists list invitations
+type InviteStore interface {
+	// Create stores a new invite
+	Create(ctx context.Context, invite *models.Invite) error
+	// FindByID returns the invite with the given ID
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invite, error)
+	// FindActiveForList returns the list's invites that are unrevoked, unexpired and not used up
+	FindActiveForList(ctx context.Context, listID primitive.ObjectID, now time.Time) ([]models.Invite, error)
+	// Consume atomically records one use of an invite and returns it. Returns ErrTokenUsed
+	// if the invite is revoked, expired or has no uses left.
+	Consume(ctx context.Context, id primitive.ObjectID, now time.Time) (*models.Invite, error)
+	// Release gives back a use recorded by Consume when the redemption didn't go through
+	Release(ctx context.Context, id primitive.ObjectID) error
+	// Revoke revokes an invite
+	Revoke(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) error
+	// RevokeCreatedBy revokes every invite the user created
+	RevokeCreatedBy(ctx context.Context, userID primitive.ObjectID, revokedAt time.Time) error
+	// RevokeCreatedByOnList revokes every invite the user created for the list
+	RevokeCreatedByOnList(ctx context.Context, listID, userID primitive.ObjectID, revokedAt time.Time) error
+}
+
+// RateLimitStore keeps fixed-window counters for rate limiting and account lockout
+type RateLimitStore interface {
+	// Increment adds one to the key's counter and returns the new count and when the window ends.
+	// A counter whose window has ended starts again from one with a new window of the given length.
+	Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int, time.Time, error)
+	// Peek returns the key's count and when its window ends, or zero if the window has ended
+	Peek(ctx context.Context, key string, now time.Time) (int, time.Time, error)
+	// Reset clears the key's counter
+	Reset(ctx context.Context, key string) error
+}
+
+// Stores bundles every store the API depends on
+type Stores struct {
+	Users          UserStore
+	Lists          ListStore
+	RefreshTokens  RefreshTokenStore
+	Revocations    RevocationStore
+	Invites        InviteStore
+	PasswordResets PasswordResetStore
+	RateLimits     RateLimitStore
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// NewMongoStores creates every store backed by the given database
+func NewMongoStores(db *mongo.Database) Stores {
+	return Stores{
+		Users:          NewMongoUserStore(db),
+		Lists:          NewMongoListStore(db),
+		RefreshTokens:  NewMongoRefreshTokenStore(db),
+		Revocations:    NewMongoRevocationStore(db),
+		Invites:        NewMongoInviteStore(db),
+		PasswordResets: NewMongoPasswordResetStore(db),
+		RateLimits:     NewMongoRateLimitStore(db),
+	}
+}
+
+// NewMemoryStores creates every store in memory, for tests and local development
+func NewMemoryStores() Stores {
+	return Stores{
+		Users:          NewMemoryUserStore(),
+		Lists:          NewMemoryListStore(),
+		RefreshTokens:  NewMemoryRefreshTokenStore(),
+		Revocations:    NewMemoryRevocationStore(),
+		Invites:        NewMemoryInviteStore(),
+		PasswordResets: NewMemoryPasswordResetStore(),
+		RateLimits:     NewMemoryRateLimitStore(),
+	}
+}
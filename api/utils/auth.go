@@ -2,28 +2,27 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
-	"bryce-stabenow/grocer-me/config"
 	"bryce-stabenow/grocer-me/models"
+	"bryce-stabenow/grocer-me/store"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 // GetAuthenticatedUser retrieves the authenticated user ID from context and validates it
 func GetAuthenticatedUser(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
 	userIDStr, ok := GetUserID(r)
 	if !ok {
-		ErrorResponse(w, http.StatusUnauthorized, "User ID not found in context")
+		ErrorResponse(w, http.StatusUnauthorized, CodeAuthRequired, "User ID not found in context")
 		return primitive.ObjectID{}, false
 	}
 
 	userID, err := primitive.ObjectIDFromHex(userIDStr)
 	if err != nil {
-		ErrorResponse(w, http.StatusBadRequest, "Invalid user ID format")
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "Invalid user ID format")
 		return primitive.ObjectID{}, false
 	}
 
@@ -34,61 +33,107 @@ func GetAuthenticatedUser(w http.ResponseWriter, r *http.Request) (primitive.Obj
 func GetAndValidateListID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
 	listIDStr := GetPathParam(r, "id")
 	if listIDStr == "" {
-		ErrorResponse(w, http.StatusBadRequest, "List ID is required")
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "List ID is required")
 		return primitive.ObjectID{}, false
 	}
 
 	listID, err := primitive.ObjectIDFromHex(listIDStr)
 	if err != nil {
-		ErrorResponse(w, http.StatusBadRequest, "Invalid list ID format")
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "Invalid list ID format")
 		return primitive.ObjectID{}, false
 	}
 
 	return listID, true
 }
 
-// FetchList retrieves a list by ID from the database
-func FetchList(w http.ResponseWriter, listID primitive.ObjectID) (*models.List, bool) {
-	collection := config.DB.Collection("lists")
+// GetAndValidateItemID extracts and validates the list item ID from path parameters
+func GetAndValidateItemID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	itemIDStr := GetPathParam(r, "itemId")
+	if itemIDStr == "" {
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "Item ID is required")
+		return primitive.ObjectID{}, false
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(itemIDStr)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "Invalid item ID format")
+		return primitive.ObjectID{}, false
+	}
+
+	return itemID, true
+}
+
+// GetAndValidateMemberID extracts and validates a member's user ID from path parameters
+func GetAndValidateMemberID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	memberIDStr := GetPathParam(r, "userId")
+	if memberIDStr == "" {
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "User ID is required")
+		return primitive.ObjectID{}, false
+	}
+
+	memberID, err := primitive.ObjectIDFromHex(memberIDStr)
+	if err != nil {
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidID, "Invalid user ID format")
+		return primitive.ObjectID{}, false
+	}
+
+	return memberID, true
+}
+
+// FetchList retrieves a list by ID from the store
+func FetchList(w http.ResponseWriter, lists store.ListStore, listID primitive.ObjectID) (*models.List, bool) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var list models.List
-	err := collection.FindOne(ctx, bson.M{"_id": listID}).Decode(&list)
+	list, err := lists.FindByID(ctx, listID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			ErrorResponse(w, http.StatusNotFound, "List not found")
+		if errors.Is(err, store.ErrNotFound) {
+			ErrorResponse(w, http.StatusNotFound, CodeListNotFound, "List not found")
 			return nil, false
 		}
-		ErrorResponse(w, http.StatusInternalServerError, "Failed to find list")
+		ErrorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to find list")
 		return nil, false
 	}
 
-	return &list, true
+	return list, true
 }
 
-// CheckListAccess verifies if a user has access to a list (owner or shared with)
-func CheckListAccess(w http.ResponseWriter, list *models.List, userID primitive.ObjectID) bool {
-	if list.UserID == userID {
-		return true
-	}
-
-	for _, sharedUserID := range list.SharedWith {
-		if sharedUserID == userID {
-			return true
+// FindListItem returns the index of the item with the given ID in a list
+func FindListItem(w http.ResponseWriter, list *models.List, itemID primitive.ObjectID) (int, bool) {
+	for i, item := range list.Items {
+		if item.ID == itemID {
+			return i, true
 		}
 	}
 
-	ErrorResponse(w, http.StatusForbidden, "You do not have access to this list")
-	return false
+	ErrorResponse(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+	return -1, false
+}
+
+// CheckListAccess verifies if a user has access to a list (owner or any member)
+func CheckListAccess(w http.ResponseWriter, list *models.List, userID primitive.ObjectID) bool {
+	return CheckListRole(w, list, userID, models.RoleViewer)
+}
+
+// CheckListRole verifies if a user has at least the given role on a list
+func CheckListRole(w http.ResponseWriter, list *models.List, userID primitive.ObjectID, required models.Role) bool {
+	role := list.RoleOf(userID)
+	if role == "" {
+		ErrorResponse(w, http.StatusForbidden, CodeNotAMember, "You do not have access to this list")
+		return false
+	}
+	if !role.AtLeast(required) {
+		ErrorResponse(w, http.StatusForbidden, CodeInsufficientRole, "You do not have permission to perform this action")
+		return false
+	}
+	return true
 }
 
 // CheckListOwnership verifies if a user is the owner of a list
 func CheckListOwnership(w http.ResponseWriter, list *models.List, userID primitive.ObjectID) bool {
 	if list.UserID != userID {
-		ErrorResponse(w, http.StatusForbidden, "You do not have permission to perform this action")
+		ErrorResponse(w, http.StatusForbidden, CodeInsufficientRole, "You do not have permission to perform this action")
 		return false
 	}
 	return true
 }
-
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"net/http"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an error. Clients should branch on
+// the code rather than the message, which is meant for people and may change.
+type ErrorCode string
+
+// General errors
+const (
+	CodeBadRequest       ErrorCode = "bad_request"
+	CodeInvalidJSON      ErrorCode = "invalid_json"
+	CodeValidationFailed ErrorCode = "validation_failed"
+	CodeBodyTooLarge     ErrorCode = "body_too_large"
+	CodeInvalidID        ErrorCode = "invalid_id"
+	CodeRouteNotFound    ErrorCode = "route_not_found"
+	CodeMethodNotAllowed ErrorCode = "method_not_allowed"
+	CodeVersionConflict  ErrorCode = "version_conflict"
+	CodeRateLimited      ErrorCode = "rate_limited"
+	CodeInternal         ErrorCode = "internal_error"
+)
+
+// Authentication errors
+const (
+	CodeAuthRequired         ErrorCode = "auth_required"
+	CodeInvalidToken         ErrorCode = "invalid_token"
+	CodeSessionRevoked       ErrorCode = "session_revoked"
+	CodeInvalidCredentials   ErrorCode = "invalid_credentials"
+	CodeIncorrectPassword    ErrorCode = "incorrect_password"
+	CodeInvalidRefreshToken  ErrorCode = "invalid_refresh_token"
+	CodeRefreshTokenReused   ErrorCode = "refresh_token_reused"
+	CodeInvalidResetToken    ErrorCode = "invalid_reset_token"
+	CodeInvalidVerification  ErrorCode = "invalid_verification_token"
+	CodeEmailNotVerified     ErrorCode = "email_not_verified"
+	CodeEmailAlreadyVerified ErrorCode = "email_already_verified"
+	CodeEmailTaken           ErrorCode = "email_taken"
+	CodeEmailUnchanged       ErrorCode = "email_unchanged"
+)
+
+// List and sharing errors
+const (
+	CodeNotAMember        ErrorCode = "not_a_member"
+	CodeInsufficientRole  ErrorCode = "insufficient_role"
+	CodeInviteRequired    ErrorCode = "invite_required"
+	CodeInvalidInvite     ErrorCode = "invalid_invite"
+	CodeAlreadyOwner      ErrorCode = "already_owner"
+	CodeOwnerCannotLeave  ErrorCode = "owner_cannot_leave"
+	CodeCannotTargetOwner ErrorCode = "cannot_target_owner"
+)
+
+// Missing resources
+const (
+	CodeUserNotFound   ErrorCode = "user_not_found"
+	CodeListNotFound   ErrorCode = "list_not_found"
+	CodeItemNotFound   ErrorCode = "item_not_found"
+	CodeMemberNotFound ErrorCode = "member_not_found"
+	CodeInviteNotFound ErrorCode = "invite_not_found"
+)
+
+// RequestIDHeader carries the ID that ties a response to the server's logs
+const RequestIDHeader = "X-Request-ID"
+
+// APIError is the body of every error response
+type APIError struct {
+	Code      ErrorCode    `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// ErrorResponse sends a JSON error response
+func ErrorResponse(w http.ResponseWriter, statusCode int, code ErrorCode, message string) {
+	WriteError(w, statusCode, APIError{Code: code, Message: message})
+}
+
+// WriteError sends an error response, filling in the request ID the RequestID middleware assigned
+func WriteError(w http.ResponseWriter, statusCode int, apiErr APIError) {
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	JSONResponse(w, statusCode, apiErr)
+}
+
+// NotFound sends a 404 for a path that matches no route
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	ErrorResponse(w, http.StatusNotFound, CodeRouteNotFound, "No route for "+r.Method+" "+r.URL.Path)
+}
+
+// MethodNotAllowed sends a 405 for a path that has routes, but none for the request method.
+// The router sets the Allow header before calling it.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	ErrorResponse(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bryce-stabenow/grocer-me/models"
+)
+
+// ListETag returns the entity tag for the current version of a list
+func ListETag(list *models.List) string {
+	return fmt.Sprintf("\"%d\"", list.Version)
+}
+
+// SetListETag sets the ETag header for a list response
+func SetListETag(w http.ResponseWriter, list *models.List) {
+	w.Header().Set("ETag", ListETag(list))
+}
+
+// CheckIfMatch verifies the request's If-Match header against the list's current version.
+// Requests without an If-Match header are always allowed. Tags are compared strongly, as
+// RFC 7232 requires, so weak tags never match.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, list *models.List) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+
+	current := ListETag(list)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+
+	SetListETag(w, list)
+	PreconditionFailed(w)
+	return false
+}
+
+// IfMatchPinned reports whether the request's If-Match header names specific versions.
+// Requests without one, or with If-Match: *, accept whatever version is current.
+func IfMatchPinned(r *http.Request) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return false
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(tag) == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// PreconditionFailed sends a 412 response for a write that lost a version race
+func PreconditionFailed(w http.ResponseWriter) {
+	ErrorResponse(w, http.StatusPreconditionFailed, CodeVersionConflict, "List has been modified. Please reload and try again.")
+}
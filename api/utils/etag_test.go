@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchPinned(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		want    bool
+	}{
+		{"", false},
+		{`"3"`, true},
+		{`W/"3"`, true},
+		{"*", false},
+		{`"2", *`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ifMatch, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/lists/abc", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			if got := IfMatchPinned(r); got != tt.want {
+				t.Fatalf("IfMatchPinned = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes under a shared path prefix, wrapped in the group's own middleware.
+// Group middleware runs after the router's global middleware, outermost group first.
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []func(http.HandlerFunc) http.HandlerFunc
+}
+
+// Group creates a route group under prefix whose routes are wrapped in the given middleware
+func (router *Router) Group(prefix string, middlewares ...func(http.HandlerFunc) http.HandlerFunc) *Group {
+	return &Group{
+		router:      router,
+		prefix:      joinPath("", prefix),
+		middlewares: middlewares,
+	}
+}
+
+// Group creates a nested group that extends this group's prefix and middleware
+func (group *Group) Group(prefix string, middlewares ...func(http.HandlerFunc) http.HandlerFunc) *Group {
+	stack := make([]func(http.HandlerFunc) http.HandlerFunc, 0, len(group.middlewares)+len(middlewares))
+	stack = append(stack, group.middlewares...)
+	stack = append(stack, middlewares...)
+
+	return &Group{
+		router:      group.router,
+		prefix:      joinPath(group.prefix, prefix),
+		middlewares: stack,
+	}
+}
+
+// Use adds middleware to the group. It only wraps routes added after it.
+func (group *Group) Use(middleware func(http.HandlerFunc) http.HandlerFunc) {
+	group.middlewares = append(group.middlewares, middleware)
+}
+
+// AddRoute adds a route under the group's prefix, wrapped in the group's middleware
+func (group *Group) AddRoute(method, pattern string, handler http.HandlerFunc) {
+	for i := len(group.middlewares) - 1; i >= 0; i-- {
+		handler = group.middlewares[i](handler)
+	}
+	group.router.AddRoute(method, joinPath(group.prefix, pattern), handler)
+}
+
+// GET adds a GET route
+func (group *Group) GET(pattern string, handler http.HandlerFunc) {
+	group.AddRoute("GET", pattern, handler)
+}
+
+// POST adds a POST route
+func (group *Group) POST(pattern string, handler http.HandlerFunc) {
+	group.AddRoute("POST", pattern, handler)
+}
+
+// PUT adds a PUT route
+func (group *Group) PUT(pattern string, handler http.HandlerFunc) {
+	group.AddRoute("PUT", pattern, handler)
+}
+
+// PATCH adds a PATCH route
+func (group *Group) PATCH(pattern string, handler http.HandlerFunc) {
+	group.AddRoute("PATCH", pattern, handler)
+}
+
+// DELETE adds a DELETE route
+func (group *Group) DELETE(pattern string, handler http.HandlerFunc) {
+	group.AddRoute("DELETE", pattern, handler)
+}
+
+// joinPath appends a route pattern to a group prefix: "/lists" and "/:id" give "/lists/:id",
+// while "/lists" and "" or "/" give "/lists"
+func joinPath(prefix, pattern string) string {
+	prefix = strings.Trim(prefix, "/")
+	pattern = strings.Trim(pattern, "/")
+
+	switch {
+	case prefix == "" && pattern == "":
+		return "/"
+	case prefix == "":
+		return "/" + pattern
+	case pattern == "":
+		return "/" + prefix
+	default:
+		return "/" + prefix + "/" + pattern
+	}
+}
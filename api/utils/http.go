@@ -3,7 +3,13 @@ package utils
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
 )
 
 // ContextKey is a custom type for context keys to avoid collisions
@@ -14,8 +20,33 @@ const (
 	UserIDKey ContextKey = "user_id"
 	// PathParamsKey is the context key for storing path parameters
 	PathParamsKey ContextKey = "path_params"
+	// TokenKey is the context key for storing the authenticated access token's details
+	TokenKey ContextKey = "token"
+	// RequestIDKey is the context key for storing the request ID
+	RequestIDKey ContextKey = "request_id"
+	// AllowedMethodsKey is the context key for storing the methods registered for the request path
+	AllowedMethodsKey ContextKey = "allowed_methods"
+	// RoutePatternKey is the context key for storing the pattern of the matched route
+	RoutePatternKey ContextKey = "route_pattern"
+	// RequestInfoKey is the context key for storing the request's RequestInfo
+	RequestInfoKey ContextKey = "request_info"
 )
 
+// RequestInfo collects details about a request while it is handled, for the access log.
+// Inner middleware replaces the request with a copy, so details are recorded through a pointer
+// the access log shares.
+type RequestInfo struct {
+	UserID string
+}
+
+// TokenInfo describes the access token a request was authenticated with
+type TokenInfo struct {
+	ID        string
+	UserID    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
 // JSONResponse sends a JSON response with the given status code
 func JSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -25,14 +56,86 @@ func JSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
-// ErrorResponse sends a JSON error response
-func ErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	JSONResponse(w, statusCode, map[string]string{"error": message})
+// ClientIP returns the IP address of the client that made the request. With trustForwarded
+// it uses the first address in X-Forwarded-For, which a reverse proxy sets to the real client.
+func ClientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
-// DecodeJSON decodes JSON from request body
+// DecodeJSON decodes JSON from request body and validates it against the struct's `binding` tags.
+// A body that breaks the rules returns a *ValidationError.
 func DecodeJSON(r *http.Request, v interface{}) error {
-	return json.NewDecoder(r.Body).Decode(v)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// DecodeErrorResponse sends a 400 for a body DecodeJSON rejected, listing each invalid field,
+// or a 413 for one over the BodyLimit cap.
+// Decoder internals are never echoed back to the client.
+func DecodeErrorResponse(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	var typeErr *json.UnmarshalTypeError
+	var tooLargeErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &tooLargeErr):
+		ErrorResponse(w, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Request body is too large")
+	case errors.As(err, &validationErr):
+		WriteError(w, http.StatusBadRequest, APIError{
+			Code:    CodeValidationFailed,
+			Message: "Invalid request: " + validationErr.Error(),
+			Details: validationErr.Fields,
+		})
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		field := FieldError{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}
+		WriteError(w, http.StatusBadRequest, APIError{
+			Code:    CodeValidationFailed,
+			Message: "Invalid request: " + field.Field + " " + field.Message,
+			Details: []FieldError{field},
+		})
+	case errors.As(err, &typeErr):
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidJSON, "Request body must be a JSON object")
+	case errors.Is(err, io.EOF):
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidJSON, "Request body is required")
+	default:
+		ErrorResponse(w, http.StatusBadRequest, CodeInvalidJSON, "Request body is not valid JSON")
+	}
+}
+
+// jsonTypeName describes a Go type the way it appears in JSON
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
 }
 
 // GetUserID retrieves user ID from request context
@@ -43,10 +146,37 @@ func GetUserID(r *http.Request) (string, bool) {
 
 // SetUserID sets user ID in context
 func SetUserID(r *http.Request, userID string) *http.Request {
+	if info := GetRequestInfo(r); info != nil {
+		info.UserID = userID
+	}
 	ctx := context.WithValue(r.Context(), UserIDKey, userID)
 	return r.WithContext(ctx)
 }
 
+// GetRequestID retrieves the request ID from request context
+func GetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(RequestIDKey).(string)
+	return id
+}
+
+// SetRequestID sets the request ID in context
+func SetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), RequestIDKey, id)
+	return r.WithContext(ctx)
+}
+
+// GetToken retrieves the authenticated access token's details from request context
+func GetToken(r *http.Request) (TokenInfo, bool) {
+	token, ok := r.Context().Value(TokenKey).(TokenInfo)
+	return token, ok
+}
+
+// SetToken sets the authenticated access token's details in context
+func SetToken(r *http.Request, token TokenInfo) *http.Request {
+	ctx := context.WithValue(r.Context(), TokenKey, token)
+	return r.WithContext(ctx)
+}
+
 // GetPathParam retrieves a path parameter from context
 func GetPathParam(r *http.Request, key string) string {
 	params, ok := r.Context().Value(PathParamsKey).(map[string]string)
@@ -63,7 +193,7 @@ func SetPathParams(r *http.Request, params map[string]string) *http.Request {
 }
 
 // SetCookie sets an HTTP cookie
-func SetCookie(w http.ResponseWriter, name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+func SetCookie(w http.ResponseWriter, name, value string, maxAge int, path, domain string, secure, httpOnly bool, sameSite http.SameSite) {
 	cookie := &http.Cookie{
 		Name:     name,
 		Value:    value,
@@ -72,7 +202,43 @@ func SetCookie(w http.ResponseWriter, name, value string, maxAge int, path, doma
 		Domain:   domain,
 		Secure:   secure,
 		HttpOnly: httpOnly,
+		SameSite: sameSite,
 	}
 	http.SetCookie(w, cookie)
 }
 
+// GetAllowedMethods retrieves the methods the router has registered for the request path
+func GetAllowedMethods(r *http.Request) []string {
+	methods, _ := r.Context().Value(AllowedMethodsKey).([]string)
+	return methods
+}
+
+// SetAllowedMethods sets the methods registered for the request path in context
+func SetAllowedMethods(r *http.Request, methods []string) *http.Request {
+	ctx := context.WithValue(r.Context(), AllowedMethodsKey, methods)
+	return r.WithContext(ctx)
+}
+
+// GetRoutePattern retrieves the pattern of the route that matched the request, or "" if none did
+func GetRoutePattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(RoutePatternKey).(string)
+	return pattern
+}
+
+// SetRoutePattern sets the pattern of the matched route in context
+func SetRoutePattern(r *http.Request, pattern string) *http.Request {
+	ctx := context.WithValue(r.Context(), RoutePatternKey, pattern)
+	return r.WithContext(ctx)
+}
+
+// GetRequestInfo retrieves the request's RequestInfo, or nil if nothing is collecting it
+func GetRequestInfo(r *http.Request) *RequestInfo {
+	info, _ := r.Context().Value(RequestInfoKey).(*RequestInfo)
+	return info
+}
+
+// SetRequestInfo sets the RequestInfo that collects details about the request in context
+func SetRequestInfo(r *http.Request, info *RequestInfo) *http.Request {
+	ctx := context.WithValue(r.Context(), RequestInfoKey, info)
+	return r.WithContext(ctx)
+}
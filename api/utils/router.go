@@ -2,26 +2,24 @@ package utils
 
 import (
 	"net/http"
+	"sort"
 	"strings"
 )
 
-// Route represents a single route with its handler
-type Route struct {
-	Method  string
-	Pattern string
-	Handler http.HandlerFunc
-}
-
-// Router handles HTTP routing
+// Router handles HTTP routing, matching paths against a prefix tree of routes.
+//
+// Patterns are made of static segments, ":name" segments that match any one segment, and a
+// final "*name" segment that matches the rest of the path. Registering a route that conflicts
+// with an existing one panics.
 type Router struct {
-	routes      []Route
+	root        *node
 	middlewares []func(http.HandlerFunc) http.HandlerFunc
 }
 
 // NewRouter creates a new router
 func NewRouter() *Router {
 	return &Router{
-		routes:      []Route{},
+		root:        &node{},
 		middlewares: []func(http.HandlerFunc) http.HandlerFunc{},
 	}
 }
@@ -33,11 +31,7 @@ func (router *Router) Use(middleware func(http.HandlerFunc) http.HandlerFunc) {
 
 // AddRoute adds a route to the router
 func (router *Router) AddRoute(method, pattern string, handler http.HandlerFunc) {
-	router.routes = append(router.routes, Route{
-		Method:  method,
-		Pattern: pattern,
-		Handler: handler,
-	})
+	router.root.insert(method, pattern, handler)
 }
 
 // GET adds a GET route
@@ -55,40 +49,63 @@ func (router *Router) PUT(pattern string, handler http.HandlerFunc) {
 	router.AddRoute("PUT", pattern, handler)
 }
 
+// PATCH adds a PATCH route
+func (router *Router) PATCH(pattern string, handler http.HandlerFunc) {
+	router.AddRoute("PATCH", pattern, handler)
+}
+
 // DELETE adds a DELETE route
 func (router *Router) DELETE(pattern string, handler http.HandlerFunc) {
 	router.AddRoute("DELETE", pattern, handler)
 }
 
-// ServeHTTP implements the http.Handler interface
+// ServeHTTP implements the http.Handler interface.
+//
+// A path with routes for other methods gets a 405 with an Allow header. HEAD is answered by the
+// GET route and OPTIONS by listing the allowed methods, unless either is registered explicitly.
 func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Create a handler that will process the request
 	var finalHandler http.HandlerFunc
-	
-	// Find matching route
-	routeFound := false
-	for _, route := range router.routes {
-		if route.Method != r.Method {
-			continue
-		}
-
-		params, matches := matchPattern(route.Pattern, r.URL.Path)
-		if matches {
-			routeFound = true
-			
-			// Apply path parameters to request context
-			if len(params) > 0 {
-				r = SetPathParams(r, params)
+	var params []pathParam
+
+	// Find the routes for the path
+	if n := router.root.lookup(cleanPath(r.URL.Path), &params); n != nil {
+		// Record the route and allowed methods for logging and CORS preflight
+		r = SetRoutePattern(r, n.pattern)
+		r = SetAllowedMethods(r, n.allowed)
+
+		finalHandler = n.handlers[r.Method]
+		if finalHandler == nil {
+			switch r.Method {
+			case http.MethodHead:
+				// net/http drops the body of a response to a HEAD request
+				finalHandler = n.handlers[http.MethodGet]
+			case http.MethodOptions:
+				finalHandler = func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNoContent)
+				}
 			}
-
-			finalHandler = route.Handler
-			break
+		}
+		if finalHandler == nil || r.Method == http.MethodOptions {
+			w.Header().Set("Allow", strings.Join(n.allowed, ", "))
+		}
+		if finalHandler == nil {
+			finalHandler = MethodNotAllowed
 		}
 	}
 
 	// If no route found, use 404 handler
-	if !routeFound {
-		finalHandler = http.NotFound
+	if finalHandler == nil {
+		finalHandler = NotFound
+	}
+
+	// Apply path parameters to request context
+	if len(params) > 0 {
+		paramMap := make(map[string]string, len(params))
+		for _, param := range params {
+			paramMap[param.name] = param.value
+		}
+		r = SetPathParams(r, paramMap)
 	}
 
 	// Apply global middlewares to the handler
@@ -100,38 +117,42 @@ func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	finalHandler(w, r)
 }
 
-// matchPattern matches a URL pattern against a path and extracts parameters
-// Pattern format: "/lists/:id" matches "/lists/123" with params["id"] = "123"
-func matchPattern(pattern, path string) (map[string]string, bool) {
-	params := make(map[string]string)
-	
-	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
-	pathParts := strings.Split(strings.Trim(path, "/"), "/")
-
-	// Handle root path
-	if pattern == "/" && path == "/" {
-		return params, true
-	}
+// methodOrder is the order methods are listed in an Allow header
+var methodOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
 
-	// Different number of parts means no match
-	if len(patternParts) != len(pathParts) {
-		return nil, false
+// allowedMethods lists the methods a path answers: the registered ones, HEAD wherever there is
+// a GET, and always OPTIONS
+func allowedMethods(registered map[string]http.HandlerFunc) []string {
+	answered := make(map[string]bool, len(registered)+2)
+	for method := range registered {
+		answered[method] = true
+	}
+	if registered[http.MethodGet] != nil {
+		answered[http.MethodHead] = true
 	}
+	answered[http.MethodOptions] = true
 
-	for i := 0; i < len(patternParts); i++ {
-		patternPart := patternParts[i]
-		pathPart := pathParts[i]
-
-		if strings.HasPrefix(patternPart, ":") {
-			// This is a parameter
-			paramName := strings.TrimPrefix(patternPart, ":")
-			params[paramName] = pathPart
-		} else if patternPart != pathPart {
-			// Not a parameter and doesn't match
-			return nil, false
+	allowed := make([]string, 0, len(answered))
+	for _, method := range methodOrder {
+		if answered[method] {
+			allowed = append(allowed, method)
+			delete(answered, method)
 		}
 	}
 
-	return params, true
+	// Any other methods go last, alphabetically
+	var others []string
+	for method := range answered {
+		others = append(others, method)
+	}
+	sort.Strings(others)
+	return append(allowed, others...)
 }
-
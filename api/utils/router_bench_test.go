@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// benchRoutes mirrors the API's route table
+var benchRoutes = []struct {
+	method  string
+	pattern string
+}{
+	{"GET", "/health"},
+	{"POST", "/token/refresh"},
+	{"POST", "/logout"},
+	{"POST", "/lists/share/:id"},
+	{"POST", "/signup"},
+	{"POST", "/signin"},
+	{"POST", "/password/forgot"},
+	{"POST", "/password/reset"},
+	{"GET", "/verify-email"},
+	{"GET", "/me"},
+	{"PATCH", "/me"},
+	{"POST", "/logout/all"},
+	{"POST", "/me/password"},
+	{"POST", "/me/email"},
+	{"DELETE", "/me"},
+	{"POST", "/verify-email/resend"},
+	{"POST", "/lists"},
+	{"GET", "/lists"},
+	{"GET", "/lists/:id"},
+	{"GET", "/lists/:id/events"},
+	{"PUT", "/lists/:id"},
+	{"DELETE", "/lists/:id"},
+	{"POST", "/lists/:id/invites"},
+	{"GET", "/lists/:id/invites"},
+	{"DELETE", "/lists/:id/invites/:inviteId"},
+	{"PUT", "/lists/:id/members/:userId"},
+	{"DELETE", "/lists/:id/members/:userId"},
+	{"POST", "/lists/:id/leave"},
+	{"POST", "/lists/:id/transfer"},
+	{"POST", "/lists/:id/items"},
+	{"PUT", "/lists/:id/items/:itemId"},
+	{"DELETE", "/lists/:id/items/:itemId"},
+	{"PUT", "/lists/:id/items/:itemId/checked"},
+}
+
+// benchRequests are looked up in turn by each benchmark
+var benchRequests = []struct {
+	method string
+	path   string
+}{
+	{"GET", "/health"},
+	{"POST", "/signin"},
+	{"GET", "/lists"},
+	{"GET", "/lists/6ad3b9fbbfe726cd53eee145"},
+	{"POST", "/lists/6ad3b9fbbfe726cd53eee145/items"},
+	{"PUT", "/lists/6ad3b9fbbfe726cd53eee145/items/6ad3b9fbbfe726cd53eee146/checked"},
+	{"DELETE", "/lists/6ad3b9fbbfe726cd53eee145/members/6ad3b9fbbfe726cd53eee142"},
+}
+
+// linearRoute and linearMatch are the router's original matcher, which scanned every route
+// and split the pattern and path on each attempt. They are kept as a baseline.
+type linearRoute struct {
+	method  string
+	pattern string
+}
+
+func linearMatch(routes []linearRoute, method, path string) (map[string]string, bool) {
+	for _, route := range routes {
+		if route.method != method {
+			continue
+		}
+		if params, matches := matchPattern(route.pattern, path); matches {
+			return params, true
+		}
+	}
+	return nil, false
+}
+
+func matchPattern(pattern, path string) (map[string]string, bool) {
+	params := make(map[string]string)
+
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if pattern == "/" && path == "/" {
+		return params, true
+	}
+
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	for i := 0; i < len(patternParts); i++ {
+		if strings.HasPrefix(patternParts[i], ":") {
+			params[strings.TrimPrefix(patternParts[i], ":")] = pathParts[i]
+		} else if patternParts[i] != pathParts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func newBenchRouter() *Router {
+	router := NewRouter()
+	for _, route := range benchRoutes {
+		router.AddRoute(route.method, route.pattern, func(w http.ResponseWriter, r *http.Request) {})
+	}
+	return router
+}
+
+func BenchmarkTreeMatch(b *testing.B) {
+	router := newBenchRouter()
+	params := make([]pathParam, 0, 4)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		req := benchRequests[i%len(benchRequests)]
+		params = params[:0]
+		n := router.root.lookup(cleanPath(req.path), &params)
+		if n == nil || n.handlers[req.method] == nil {
+			b.Fatalf("no route for %s %s", req.method, req.path)
+		}
+	}
+}
+
+func BenchmarkLinearMatch(b *testing.B) {
+	routes := make([]linearRoute, len(benchRoutes))
+	for i, route := range benchRoutes {
+		routes[i] = linearRoute{method: route.method, pattern: route.pattern}
+	}
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		req := benchRequests[i%len(benchRequests)]
+		if _, ok := linearMatch(routes, req.method, req.path); !ok {
+			b.Fatalf("no route for %s %s", req.method, req.path)
+		}
+	}
+}
+
+func BenchmarkRouterServeHTTP(b *testing.B) {
+	router := newBenchRouter()
+	requests := make([]*http.Request, len(benchRequests))
+	for i, req := range benchRequests {
+		requests[i] = httptest.NewRequest(req.method, req.path, nil)
+	}
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		router.ServeHTTP(w, requests[i%len(requests)])
+	}
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// echo responds with the route it was registered for and the given path params
+func echo(route string, params ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := route
+		for _, name := range params {
+			body += " " + name + "=" + GetPathParam(r, name)
+		}
+		w.Write([]byte(body))
+	}
+}
+
+func TestRouterMatching(t *testing.T) {
+	router := NewRouter()
+	router.GET("/lists", echo("lists"))
+	router.GET("/lists/share", echo("share"))
+	router.GET("/lists/:id", echo("list", "id"))
+	router.GET("/lists/:id/items/:itemId", echo("item", "id", "itemId"))
+	router.GET("/lists/share/extra", echo("extra"))
+	router.GET("/static/*path", echo("static", "path"))
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/lists", http.StatusOK, "lists"},
+		{"/lists/", http.StatusOK, "lists"},
+		{"/lists/share", http.StatusOK, "share"},
+		{"/lists/abc", http.StatusOK, "list id=abc"},
+		{"/lists/abc/items/42", http.StatusOK, "item id=abc itemId=42"},
+		// Static segments win, but matching backs off to the param when they lead nowhere
+		{"/lists/share/items/42", http.StatusOK, "item id=share itemId=42"},
+		{"/static/css/app.css", http.StatusOK, "static path=css/app.css"},
+		{"/static", http.StatusOK, "static path="},
+		{"/lists/abc/items", http.StatusNotFound, ""},
+		{"/nowhere", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Fatalf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestRouterMethods(t *testing.T) {
+	router := NewRouter()
+	router.GET("/me", echo("get"))
+	router.PATCH("/me", echo("patch"))
+
+	tests := []struct {
+		method string
+		status int
+		body   string
+		allow  string
+	}{
+		{http.MethodGet, http.StatusOK, "get", ""},
+		{http.MethodPatch, http.StatusOK, "patch", ""},
+		{http.MethodHead, http.StatusOK, "", ""},
+		{http.MethodOptions, http.StatusNoContent, "", "GET, HEAD, PATCH, OPTIONS"},
+		{http.MethodDelete, http.StatusMethodNotAllowed, "", "GET, HEAD, PATCH, OPTIONS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, "/me", nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Fatalf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Fatalf("Allow = %q, want %q", got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestGroupMiddlewareIsScoped(t *testing.T) {
+	tag := func(name string) func(http.HandlerFunc) http.HandlerFunc {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", name)
+				next(w, r)
+			}
+		}
+	}
+
+	router := NewRouter()
+	router.Use(tag("global"))
+	api := router.Group("/api", tag("api"))
+	api.GET("/open", echo("open"))
+	admin := api.Group("/admin", tag("admin"))
+	admin.GET("/users", echo("users"))
+	api.Use(tag("late"))
+	api.GET("/late", echo("late"))
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/api/open", []string{"global", "api"}},
+		{"/api/admin/users", []string{"global", "api", "admin"}},
+		{"/api/late", []string{"global", "api", "late"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			got := w.Header().Values("X-Middleware")
+			if len(got) != len(tt.want) {
+				t.Fatalf("middleware = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("middleware = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterRejectsConflictingRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+	}{
+		{"duplicate route", []string{"/lists/:id", "/lists/:id"}},
+		{"different param names", []string{"/lists/:id", "/lists/:listId/items"}},
+		{"catch-all not last", []string{"/static/*path/more"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("registering the routes did not panic")
+				}
+			}()
+			router := NewRouter()
+			for _, pattern := range tt.patterns {
+				router.GET(pattern, echo(pattern))
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// node is a node in the router's compressed prefix tree.
+//
+// A static node matches its prefix byte for byte, and its static children split the path
+// wherever two routes diverge. A param node matches one non-empty path segment, and a
+// catch-all node matches the rest of the path, if any. Matching tries static children first,
+// then the param child, then the catch-all child, so "/lists/share" beats "/lists/:id".
+type node struct {
+	prefix   string
+	static   []*node
+	param    *node
+	catchAll *node
+	name     string // Parameter name of a param or catch-all node
+
+	pattern  string // The first pattern registered at this node
+	handlers map[string]http.HandlerFunc
+	allowed  []string // Methods answered here, in Allow header order
+}
+
+// pathParam is a path parameter captured while matching
+type pathParam struct {
+	name  string
+	value string
+}
+
+// cleanPath gives a path a single leading slash and no trailing slash, so "/lists/" and
+// "lists" route the same as "/lists"
+func cleanPath(path string) string {
+	if path == "/" || (strings.HasPrefix(path, "/") && !strings.HasSuffix(path, "/")) {
+		return path
+	}
+	return "/" + strings.Trim(path, "/")
+}
+
+// insert registers a handler for the method at the pattern below n. It panics if the pattern
+// is malformed or conflicts with a route already registered.
+func (n *node) insert(method, pattern string, handler http.HandlerFunc) {
+	pattern = cleanPath(pattern)
+	path := pattern
+	target := n
+
+	for path != "" {
+		switch {
+		case path[0] == ':':
+			name, rest := cutSegment(path[1:])
+			checkParamName(pattern, ":", name)
+			if target.param == nil {
+				target.param = &node{name: name}
+			} else if target.param.name != name {
+				panic(fmt.Sprintf("router: %s conflicts with %s: :%s and :%s name the same segment",
+					pattern, target.param.firstPattern(), name, target.param.name))
+			}
+			target, path = target.param, rest
+
+		case strings.HasPrefix(path, "/*"):
+			name, rest := cutSegment(path[2:])
+			checkParamName(pattern, "*", name)
+			if rest != "" {
+				panic("router: catch-all *" + name + " must be the last segment of " + pattern)
+			}
+			if target.catchAll == nil {
+				target.catchAll = &node{name: name}
+			} else if target.catchAll.name != name {
+				panic(fmt.Sprintf("router: %s conflicts with %s: *%s and *%s name the same segments",
+					pattern, target.catchAll.firstPattern(), name, target.catchAll.name))
+			}
+			target, path = target.catchAll, rest
+
+		default:
+			// Static text runs up to the next parameter, or the slash before a catch-all
+			end := strings.IndexAny(path, ":*")
+			if end == -1 {
+				end = len(path)
+			} else if path[end-1] != '/' {
+				panic("router: parameters must start a segment in " + pattern)
+			} else if path[end] == '*' {
+				end--
+			}
+			target, path = target.addStatic(path[:end]), path[end:]
+		}
+	}
+
+	if target.handlers == nil {
+		target.handlers = make(map[string]http.HandlerFunc)
+		target.pattern = pattern
+	}
+	if _, exists := target.handlers[method]; exists {
+		panic(fmt.Sprintf("router: %s %s conflicts with %s %s", method, pattern, method, target.pattern))
+	}
+	target.handlers[method] = handler
+	target.allowed = allowedMethods(target.handlers)
+}
+
+// addStatic walks or extends the static children of n to match text and returns the node
+// that ends with it, splitting a child whose prefix only partly matches
+func (n *node) addStatic(text string) *node {
+	for text != "" {
+		var child *node
+		var index int
+		for i, c := range n.static {
+			if c.prefix[0] == text[0] {
+				child, index = c, i
+				break
+			}
+		}
+		if child == nil {
+			child = &node{prefix: text}
+			n.static = append(n.static, child)
+			return child
+		}
+
+		common := commonPrefixLength(child.prefix, text)
+		if common < len(child.prefix) {
+			split := &node{prefix: child.prefix[:common], static: []*node{child}}
+			child.prefix = child.prefix[common:]
+			n.static[index] = split
+			child = split
+		}
+		n, text = child, text[common:]
+	}
+	return n
+}
+
+// lookup finds the node whose routes match path, appending the parameters it captures to
+// params. Returns nil if no route matches.
+func (n *node) lookup(path string, params *[]pathParam) *node {
+	if !strings.HasPrefix(path, n.prefix) {
+		return nil
+	}
+	rest := path[len(n.prefix):]
+
+	if rest == "" && n.handlers != nil {
+		return n
+	}
+
+	// Static segments take priority over parameters
+	if rest != "" {
+		for _, child := range n.static {
+			if child.prefix[0] != rest[0] {
+				continue
+			}
+			if found := child.lookup(rest, params); found != nil {
+				return found
+			}
+			break
+		}
+	}
+
+	if n.param != nil && rest != "" && rest[0] != '/' {
+		value, after := cutSegment(rest)
+		*params = append(*params, pathParam{name: n.param.name, value: value})
+		if found := n.param.lookup(after, params); found != nil {
+			return found
+		}
+		*params = (*params)[:len(*params)-1]
+	}
+
+	// A catch-all matches the bare path before it too, with an empty value
+	if n.catchAll != nil && (rest == "" || rest[0] == '/') {
+		*params = append(*params, pathParam{name: n.catchAll.name, value: strings.TrimPrefix(rest, "/")})
+		return n.catchAll
+	}
+
+	return nil
+}
+
+// firstPattern returns the pattern of a route at or below n, for conflict messages
+func (n *node) firstPattern() string {
+	if n.handlers != nil {
+		return n.pattern
+	}
+	for _, child := range n.children() {
+		if pattern := child.firstPattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// children returns every child of n in matching order
+func (n *node) children() []*node {
+	children := append([]*node(nil), n.static...)
+	if n.param != nil {
+		children = append(children, n.param)
+	}
+	if n.catchAll != nil {
+		children = append(children, n.catchAll)
+	}
+	return children
+}
+
+// cutSegment splits a path at its first slash
+func cutSegment(path string) (segment, rest string) {
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		return path[:i], path[i:]
+	}
+	return path, ""
+}
+
+// checkParamName panics unless name is a usable parameter name
+func checkParamName(pattern, sigil, name string) {
+	if name == "" || strings.ContainsAny(name, ":*") {
+		panic("router: invalid parameter " + sigil + name + " in " + pattern)
+	}
+}
+
+// commonPrefixLength returns how many leading bytes a and b share
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError describes why one field of a request body is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by DecodeJSON when a body breaks its struct's binding rules
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks a struct's fields against their `binding` tags and returns a *ValidationError
+// listing every field that fails. Rules are comma separated and checked in order:
+//
+//	required   the field must be set; strings must not be blank
+//	omitempty  skip the remaining rules when the field is nil, or empty if it isn't a pointer
+//	notblank   strings must contain more than whitespace
+//	email      the string must be a plain email address
+//	min=N      strings need at least N characters, numbers at least N, slices at least N elements
+//	max=N      the same, as an upper bound
+//	oneof=a b  the value must be one of the space separated options
+//
+// Pointers are followed, so a nil pointer only fails required. Tags are parsed once per type;
+// register request types with RegisterBindings so a bad tag panics at startup.
+func Validate(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	bound, err := typeBindings(value.Type())
+	if err != nil {
+		panic(err)
+	}
+
+	var fields []FieldError
+	for _, field := range bound {
+		if message := checkRules(value.Field(field.index), field.rules); message != "" {
+			fields = append(fields, FieldError{Field: field.name, Message: message})
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// RegisterBindings parses the binding tags of each struct up front and panics if any are invalid
+func RegisterBindings(vs ...interface{}) {
+	for _, v := range vs {
+		if _, err := typeBindings(reflect.TypeOf(v)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// bindingRule is one parsed rule from a binding tag
+type bindingRule struct {
+	name    string
+	param   string
+	limit   int      // min and max
+	options []string // oneof
+}
+
+// boundField is a struct field with binding rules
+type boundField struct {
+	index int
+	name  string
+	rules []bindingRule
+}
+
+// bindings caches the parsed binding rules of each struct type
+var bindings sync.Map // reflect.Type -> []boundField
+
+// typeBindings returns the struct type's binding rules, parsing and caching them on first use
+func typeBindings(t reflect.Type) ([]boundField, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if cached, ok := bindings.Load(t); ok {
+		return cached.([]boundField), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	var fields []boundField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("binding")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		rules, err := parseRules(field.Type, tag)
+		if err != nil {
+			return nil, fmt.Errorf("utils: %s.%s: %w", t.Name(), field.Name, err)
+		}
+		fields = append(fields, boundField{index: i, name: jsonFieldName(field), rules: rules})
+	}
+
+	bindings.Store(t, fields)
+	return fields, nil
+}
+
+// parseRules parses a binding tag and checks each rule applies to the field's type
+func parseRules(fieldType reflect.Type, tag string) ([]bindingRule, error) {
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	kind := fieldType.Kind()
+
+	var rules []bindingRule
+	for _, text := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(text, "=")
+		rule := bindingRule{name: name, param: param}
+
+		switch name {
+		case "required", "omitempty":
+		case "notblank", "email":
+			if kind != reflect.String {
+				return nil, fmt.Errorf("binding rule %q only applies to strings, not %s", text, kind)
+			}
+		case "min", "max":
+			limit, err := strconv.Atoi(param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid binding rule %q", text)
+			}
+			if _, ok := measurable[kind]; !ok {
+				return nil, fmt.Errorf("binding rule %q doesn't apply to %s", text, kind)
+			}
+			rule.limit = limit
+		case "oneof":
+			rule.options = strings.Fields(param)
+			if len(rule.options) == 0 {
+				return nil, fmt.Errorf("binding rule %q has no options", text)
+			}
+		default:
+			return nil, fmt.Errorf("unknown binding rule %q", text)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// checkRules returns the message for the first rule the value breaks, or "" if it passes
+func checkRules(value reflect.Value, rules []bindingRule) string {
+	isPointer := value.Kind() == reflect.Pointer
+	for _, rule := range rules {
+		// Nil pointers have nothing else to check
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				if rule.name == "required" {
+					return "is required"
+				}
+				return ""
+			}
+			value = value.Elem()
+		}
+
+		switch rule.name {
+		case "required":
+			if value.IsZero() || (value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "") {
+				return "is required"
+			}
+		case "omitempty":
+			// A pointer that was set counts as given, even to a zero value
+			if !isPointer && value.IsZero() {
+				return ""
+			}
+		case "notblank":
+			if strings.TrimSpace(value.String()) == "" {
+				return "cannot be blank"
+			}
+		case "email":
+			address, err := mail.ParseAddress(value.String())
+			if err != nil || address.Address != value.String() {
+				return "must be a valid email address"
+			}
+		case "min":
+			if size, unit := measure(value); size < rule.limit {
+				return fmt.Sprintf("must be at least %s%s", rule.param, unit)
+			}
+		case "max":
+			if size, unit := measure(value); size > rule.limit {
+				return fmt.Sprintf("must be at most %s%s", rule.param, unit)
+			}
+		case "oneof":
+			if !containsString(rule.options, fmt.Sprint(value.Interface())) {
+				return "must be one of " + strings.Join(rule.options, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// measurable holds the kinds min and max apply to
+var measurable = map[reflect.Kind]struct{}{
+	reflect.String: {}, reflect.Slice: {}, reflect.Map: {}, reflect.Array: {},
+	reflect.Int: {}, reflect.Int8: {}, reflect.Int16: {}, reflect.Int32: {}, reflect.Int64: {},
+	reflect.Uint: {}, reflect.Uint8: {}, reflect.Uint16: {}, reflect.Uint32: {}, reflect.Uint64: {},
+	reflect.Float32: {}, reflect.Float64: {},
+}
+
+// measure returns what min and max compare against, and the unit to report it in
+func measure(value reflect.Value) (int, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len(), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint()), ""
+	default:
+		return int(value.Float()), ""
+	}
+}
+
+func containsString(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonFieldName returns the name a field has in the request body
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
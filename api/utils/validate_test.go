@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type request struct {
+		Email string  `json:"email" binding:"required,email"`
+		Name  *string `json:"name,omitempty" binding:"omitempty,notblank,max=3"`
+		Role  string  `json:"role" binding:"omitempty,oneof=viewer editor"`
+		Count int     `json:"count" binding:"min=1"`
+	}
+	blank, long := " ", "abcd"
+
+	tests := []struct {
+		name   string
+		req    request
+		fields []string
+	}{
+		{"valid", request{Email: "ann@example.com", Count: 1}, nil},
+		{"missing email", request{Count: 1}, []string{"email is required"}},
+		{"display name", request{Email: "Ann <ann@example.com>", Count: 1}, []string{"email must be a valid email address"}},
+		{"blank name", request{Email: "ann@example.com", Name: &blank, Count: 1}, []string{"name cannot be blank"}},
+		{"long name", request{Email: "ann@example.com", Name: &long, Count: 1}, []string{"name must be at most 3 characters"}},
+		{"unknown role", request{Email: "ann@example.com", Role: "owner", Count: 1}, []string{"role must be one of viewer, editor"}},
+		{"every problem", request{Role: "owner"}, []string{"email is required", "role must be one of viewer, editor", "count must be at least 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.req)
+			var got []string
+			if err != nil {
+				got = strings.Split(err.Error(), "; ")
+			}
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("Validate = %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestRegisterBindingsRejectsBadTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"unknown rule", struct {
+			Name string `binding:"requried"`
+		}{}},
+		{"bad limit", struct {
+			Name string `binding:"min=three"`
+		}{}},
+		{"min on a bool", struct {
+			Done bool `binding:"min=1"`
+		}{}},
+		{"email on an int", struct {
+			Count *int `binding:"omitempty,email"`
+		}{}},
+		{"oneof without options", struct {
+			Role string `binding:"oneof="`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("RegisterBindings accepted the tag")
+				}
+			}()
+			RegisterBindings(tt.v)
+		})
+	}
+}
@@ -44,12 +44,24 @@ export const useAuth = () => {
         }
       }
 
-      const userData = await $fetch(`${apiUrl}/me`, {
-        method: "GET",
-        credentials: "include",
-        headers,
-        retry: false,
-      });
+      const fetchMe = () =>
+        $fetch(`${apiUrl}/me`, {
+          method: "GET",
+          credentials: "include",
+          headers,
+          retry: false,
+        });
+
+      let userData;
+      try {
+        userData = await fetchMe();
+      } catch (error) {
+        // The access token is short-lived; try to rotate it once in the browser
+        if (process.server || !(await refreshSession())) {
+          throw error;
+        }
+        userData = await fetchMe();
+      }
 
       // User is authenticated
       isAuthenticated.value = true;
@@ -67,6 +79,22 @@ export const useAuth = () => {
     }
   };
 
+  /**
+   * Exchange the refresh token cookie for a new access token
+   */
+  const refreshSession = async (): Promise<boolean> => {
+    try {
+      await $fetch(`${apiUrl}/token/refresh`, {
+        method: "POST",
+        credentials: "include",
+        retry: false,
+      });
+      return true;
+    } catch (error) {
+      return false;
+    }
+  };
+
   /**
    * Clear authentication state (for logout)
    */
@@ -119,6 +147,7 @@ export const useAuth = () => {
     checkAuth,
     clearAuth,
     refreshAuth,
+    refreshSession,
     logout,
   };
 };
@@ -3,6 +3,7 @@ export const useLists = () => {
   const apiUrl = config.public.apiUrl;
 
   interface ListItem {
+    id: string;
     name: string;
     quantity: number;
     checked: boolean;
@@ -11,17 +12,45 @@ export const useLists = () => {
     added_at: string;
   }
 
+  type Role = "viewer" | "editor" | "admin" | "owner";
+
+  interface SharedUser {
+    id: string;
+    email: string;
+    role: Role;
+  }
+
   interface List {
     id: string;
     user_id: string;
     name: string;
     description?: string;
     items: ListItem[];
-    shared_with: string[];
+    shared_with: SharedUser[];
+    role: Role;
+    version: number;
     created_at: string;
     updated_at: string;
   }
 
+  interface Invite {
+    id: string;
+    list_id: string;
+    created_by: string;
+    role: Role;
+    token?: string;
+    max_uses: number;
+    uses: number;
+    expires_at: string;
+    created_at: string;
+  }
+
+  interface CreateInviteRequest {
+    role?: Exclude<Role, "owner">;
+    expires_in_hours?: number;
+    max_uses?: number;
+  }
+
   interface CreateListRequest {
     name: string;
     description?: string;
@@ -39,21 +68,15 @@ export const useLists = () => {
   }
 
   interface UpdateListItemCheckedRequest {
-    index: number;
     checked: boolean;
   }
 
   interface UpdateListItemRequest {
-    index: number;
     name?: string;
     quantity?: number;
     details?: string;
   }
 
-  interface DeleteListItemRequest {
-    index: number;
-  }
-
   /**
    * Get headers with cookie forwarding for server-side requests
    */
@@ -145,18 +168,20 @@ export const useLists = () => {
    */
   const updateListItemChecked = async (
     listId: string,
-    itemIndex: number,
+    itemId: string,
     checked: boolean
   ): Promise<List> => {
-    return await $fetch<List>(`${apiUrl}/lists/${listId}/items/checked`, {
-      method: "PUT",
-      credentials: "include",
-      headers: getHeaders(),
-      body: {
-        index: itemIndex,
-        checked,
-      } as UpdateListItemCheckedRequest,
-    });
+    return await $fetch<List>(
+      `${apiUrl}/lists/${listId}/items/${itemId}/checked`,
+      {
+        method: "PUT",
+        credentials: "include",
+        headers: getHeaders(),
+        body: {
+          checked,
+        } as UpdateListItemCheckedRequest,
+      }
+    );
   };
 
   /**
@@ -164,17 +189,14 @@ export const useLists = () => {
    */
   const updateListItem = async (
     listId: string,
-    itemIndex: number,
-    updates: { name?: string; quantity?: number; details?: string }
+    itemId: string,
+    updates: UpdateListItemRequest
   ): Promise<List> => {
-    return await $fetch<List>(`${apiUrl}/lists/${listId}/items`, {
+    return await $fetch<List>(`${apiUrl}/lists/${listId}/items/${itemId}`, {
       method: "PUT",
       credentials: "include",
       headers: getHeaders(),
-      body: {
-        index: itemIndex,
-        ...updates,
-      } as UpdateListItemRequest,
+      body: updates,
     });
   };
 
@@ -183,15 +205,12 @@ export const useLists = () => {
    */
   const deleteListItem = async (
     listId: string,
-    itemIndex: number
+    itemId: string
   ): Promise<List> => {
-    return await $fetch<List>(`${apiUrl}/lists/${listId}/items`, {
+    return await $fetch<List>(`${apiUrl}/lists/${listId}/items/${itemId}`, {
       method: "DELETE",
       credentials: "include",
       headers: getHeaders(),
-      body: {
-        index: itemIndex,
-      } as DeleteListItemRequest,
     });
   };
 
@@ -207,13 +226,107 @@ export const useLists = () => {
   };
 
   /**
-   * Share a list - adds the current user to the list's shared_with array
+   * Share a list - adds the current user to the list's members using an invite token
    */
-  const shareList = async (listId: string): Promise<List> => {
+  const shareList = async (listId: string, token: string): Promise<List> => {
     return await $fetch<List>(`${apiUrl}/lists/share/${listId}`, {
       method: "POST",
       credentials: "include",
       headers: getHeaders(),
+      body: { token },
+    });
+  };
+
+  /**
+   * Create an invite link for a list (admins and the owner)
+   */
+  const createInvite = async (
+    listId: string,
+    data: CreateInviteRequest = {}
+  ): Promise<Invite> => {
+    return await $fetch<Invite>(`${apiUrl}/lists/${listId}/invites`, {
+      method: "POST",
+      credentials: "include",
+      headers: getHeaders(),
+      body: data,
+    });
+  };
+
+  /**
+   * Get the active invites for a list (admins and the owner)
+   */
+  const getInvites = async (listId: string): Promise<Invite[]> => {
+    return await $fetch<Invite[]>(`${apiUrl}/lists/${listId}/invites`, {
+      method: "GET",
+      credentials: "include",
+      headers: getHeaders(),
+    });
+  };
+
+  /**
+   * Change a member's role (admins and the owner)
+   */
+  const updateMemberRole = async (
+    listId: string,
+    userId: string,
+    role: Exclude<Role, "owner">
+  ): Promise<List> => {
+    return await $fetch<List>(`${apiUrl}/lists/${listId}/members/${userId}`, {
+      method: "PUT",
+      credentials: "include",
+      headers: getHeaders(),
+      body: { role },
+    });
+  };
+
+  /**
+   * Remove a member from a list (admins and the owner)
+   */
+  const removeMember = async (listId: string, userId: string): Promise<List> => {
+    return await $fetch<List>(`${apiUrl}/lists/${listId}/members/${userId}`, {
+      method: "DELETE",
+      credentials: "include",
+      headers: getHeaders(),
+    });
+  };
+
+  /**
+   * Leave a list the current user is a member of
+   */
+  const leaveList = async (listId: string): Promise<List> => {
+    return await $fetch<List>(`${apiUrl}/lists/${listId}/leave`, {
+      method: "POST",
+      credentials: "include",
+      headers: getHeaders(),
+    });
+  };
+
+  /**
+   * Hand a list to another member (owner only)
+   */
+  const transferOwnership = async (
+    listId: string,
+    userId: string
+  ): Promise<List> => {
+    return await $fetch<List>(`${apiUrl}/lists/${listId}/transfer`, {
+      method: "POST",
+      credentials: "include",
+      headers: getHeaders(),
+      body: { user_id: userId },
+    });
+  };
+
+  /**
+   * Revoke an invite so its link stops working (admins and the owner)
+   */
+  const revokeInvite = async (
+    listId: string,
+    inviteId: string
+  ): Promise<void> => {
+    await $fetch(`${apiUrl}/lists/${listId}/invites/${inviteId}`, {
+      method: "DELETE",
+      credentials: "include",
+      headers: getHeaders(),
     });
   };
 
@@ -228,5 +341,12 @@ export const useLists = () => {
     deleteListItem,
     deleteList,
     shareList,
+    createInvite,
+    getInvites,
+    revokeInvite,
+    updateMemberRole,
+    removeMember,
+    leaveList,
+    transferOwnership,
   };
 };
@@ -15,8 +15,8 @@ export default defineNuxtRouteMiddleware(async (to) => {
     return navigateTo("/dashboard");
   }
 
-  // If trying to access dashboard without authentication, redirect to signin
-  if (to.path === "/dashboard" && !authenticated) {
+  // If trying to access dashboard or account pages without authentication, redirect to signin
+  if ((to.path === "/dashboard" || to.path === "/account") && !authenticated) {
     return navigateTo("/signin");
   }
 